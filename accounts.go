@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	common "github.com/apiheat/akamai-cli-common"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// Commands which can run against many accounts given with --ask
+var multiAccountCommands = []string{"gtm properties", "diagnostic-link list", "ip is-cdn-ip"}
+
+// accountClient is API client for single account switch key
+type accountClient struct {
+	key    string
+	client diagnosticClient
+}
+
+// accountClients are set instead of apiClient when --ask lists many accounts
+var accountClients []accountClient
+
+// accountResult holds outcome of a command executed against single account
+type accountResult struct {
+	Account string      `json:"account"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	err     error
+}
+
+// accountReport is the merged outcome of a command executed against many
+// accounts, keyed by account switch key
+type accountReport map[string]accountResult
+
+func (r accountReport) tableHeader() []string {
+	return []string{"account", "status", "error", "result"}
+}
+
+func (r accountReport) tableRows() [][]string {
+	var rows [][]string
+	for _, account := range r.accounts() {
+		res := r[account]
+
+		status, result := "ok", outputJSON(res.Result)
+		if res.Error != "" {
+			status, result = "failed", ""
+		}
+
+		rows = append(rows, []string{account, status, res.Error, result})
+	}
+
+	return rows
+}
+
+func (r accountReport) failure() error {
+	var errs []error
+	for _, res := range r {
+		if res.err != nil {
+			errs = append(errs, res.err)
+		}
+	}
+
+	return allFailed(errs, len(r))
+}
+
+func (r accountReport) accounts() []string {
+	var accounts []string
+	for account := range r {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+
+	return accounts
+}
+
+// accountFlags replace account switch key flag of common flags with one
+// accepting many keys and add concurrency cap of accounts
+func accountFlags(flags []cli.Flag) []cli.Flag {
+	var result []cli.Flag
+	for _, flag := range flags {
+		if flag.GetName() == "account-switch-key, ask" {
+			flag = cli.StringFlag{
+				Name:  "account-switch-key, ask",
+				Value: "",
+				Usage: "Account Switch Key (ASK). Comma separated list of `KEYS` or @FILE with one key per line runs 'gtm properties', 'diagnostic-link list' and 'ip is-cdn-ip' against every account",
+			}
+		}
+		result = append(result, flag)
+	}
+
+	return append(result, cli.IntFlag{
+		Name:  "account-workers",
+		Value: 5,
+		Usage: "`Number` of accounts processed concurrently when --ask lists many accounts",
+	})
+}
+
+// parseAccountSwitchKeys reads account switch keys from comma separated list
+// or, when spec starts with '@', from file. Empty lines and lines starting
+// with '#' are ignored in file.
+func parseAccountSwitchKeys(spec string) ([]string, error) {
+	var keys []string
+
+	if strings.HasPrefix(spec, "@") {
+		f, err := os.Open(strings.TrimPrefix(spec, "@"))
+		if err != nil {
+			return nil, newValidationError("Cannot read account switch keys: %s", err)
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				keys = append(keys, line)
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, newValidationError("Cannot read account switch keys: %s", err)
+		}
+
+		if len(keys) == 0 {
+			return nil, newValidationError("No account switch keys found in '%s'", strings.TrimPrefix(spec, "@"))
+		}
+	} else {
+		for _, key := range common.StringToStringsArr(spec) {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return common.RemoveStringDuplicates(keys), nil
+}
+
+// multiAccountCommand tells whether command line runs command which
+// supports many accounts, or only asks for help
+func multiAccountCommand(args []string) bool {
+	for _, arg := range args {
+		if arg == "--help" || arg == "-h" {
+			return true
+		}
+	}
+
+	if len(args) > 0 && args[0] == "help" {
+		return true
+	}
+
+	if len(args) < 2 {
+		return false
+	}
+
+	return common.IsStringInSlice(args[0]+" "+args[1], multiAccountCommands)
+}
+
+// runOnAccounts executes fn with API client of every account given with
+// --ask, at most 'account-workers' accounts at a time, and prints results
+// keyed by account. With single account result of fn is printed as is.
+func runOnAccounts(c *cli.Context, fn func(client diagnosticClient) (interface{}, error)) error {
+	if len(accountClients) == 0 {
+		result, err := fn(apiClient)
+		if err != nil {
+			return newAPIError(err)
+		}
+
+		if err := printOutput(c, result); err != nil {
+			return err
+		}
+
+		return failureOf(result)
+	}
+
+	clients := map[string]diagnosticClient{}
+	var keys []string
+	for _, a := range accountClients {
+		clients[a.key] = a.client
+		keys = append(keys, a.key)
+	}
+
+	var (
+		mu         sync.Mutex
+		report     = accountReport{}
+		validation error
+	)
+
+	forEachConcurrently(keys, c.GlobalInt("account-workers"), func(key string) {
+		log.Debugf("Running against account %s", key)
+
+		res := accountResult{Account: key}
+		result, err := fn(clients[key])
+		if err != nil {
+			res.err = newAPIError(err)
+			res.Error = errorText(err)
+			log.Warnf("Account %s failed: %s", key, res.Error)
+		} else {
+			// Account whose every batch target failed counts as failed
+			res.Result, res.err = result, failureOf(result)
+		}
+
+		mu.Lock()
+		report[key] = res
+		if _, ok := err.(validationError); ok {
+			validation = err
+		}
+		mu.Unlock()
+	})
+
+	// Invalid command line fails the same way for every account
+	if validation != nil {
+		return validation
+	}
+
+	if err := printOutput(c, report); err != nil {
+		return err
+	}
+
+	return report.failure()
+}
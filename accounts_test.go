@@ -0,0 +1,158 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// runCommandAs executes application against fake API for every given account
+func runCommandAs(t *testing.T, api *fakeAPI, keys []string, args ...string) (string, error) {
+	t.Helper()
+
+	for _, key := range keys {
+		accountClients = append(accountClients, accountClient{key: key, client: api.clientFor(key)})
+	}
+	defer func() { accountClients = nil }()
+
+	return runCommand(t, api, args...)
+}
+
+func TestParseAccountSwitchKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "accounts.txt")
+	ioutil.WriteFile(file, []byte("# partner accounts\n1-ABC:1-2345\n\n  1-DEF:1-6789  \n1-ABC:1-2345\n"), 0600)
+
+	empty := filepath.Join(dir, "empty.txt")
+	ioutil.WriteFile(empty, []byte("# nothing here\n"), 0600)
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{"none", "", nil, false},
+		{"single", "1-ABC:1-2345", []string{"1-ABC:1-2345"}, false},
+		{"list", "1-ABC:1-2345, 1-DEF:1-6789,", []string{"1-ABC:1-2345", "1-DEF:1-6789"}, false},
+		{"file", "@" + file, []string{"1-ABC:1-2345", "1-DEF:1-6789"}, false},
+		{"empty file", "@" + empty, nil, true},
+		{"missing file", "@" + filepath.Join(dir, "missing.txt"), nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAccountSwitchKeys(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAccountSwitchKeys() error = %v, want error %t", err, tt.wantErr)
+			}
+
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAccountSwitchKeys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultiAccountCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"gtm", "properties"}, true},
+		{[]string{"ip", "is-cdn-ip", "--input", "ips.txt"}, true},
+		{[]string{"diagnostic-link", "list"}, true},
+		{[]string{"gtm", "ip-addresses", "--domain", "example.akadns.net", "www"}, false},
+		{[]string{"ghost", "locations", "--help"}, true},
+		{[]string{"translate-error"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := multiAccountCommand(tt.args); got != tt.want {
+			t.Errorf("multiAccountCommand(%v) = %t, want %t", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestMultiAccountCommands(t *testing.T) {
+	api := newFakeAPI()
+	defer api.Close()
+
+	accounts := []string{"1-ABC:1-2345", "1-DEF:1-6789", fakeForbidden}
+
+	tests := []struct {
+		name     string
+		args     []string
+		exitCode int
+		contains []string
+	}{
+		{
+			name:     "gtm properties",
+			args:     []string{"gtm", "properties"},
+			contains: []string{`"1-ABC:1-2345": {`, `"account": "1-DEF:1-6789"`, `"hostName": "www.example.akadns.net"`, `"error": "Forbidden: fake API failure"`},
+		},
+		{
+			name:     "diagnostic-link list",
+			args:     []string{"diagnostic-link", "list"},
+			contains: []string{`"account": "1-ABC:1-2345"`, `"name": "beloved-customer"`},
+		},
+		{
+			name:     "is-cdn-ip",
+			args:     []string{"ip", "is-cdn-ip", "23.15.7.10"},
+			contains: []string{`"account": "1-DEF:1-6789"`, `"isCdnIp": true`},
+		},
+		{
+			name:     "is-cdn-ip without IP",
+			args:     []string{"ip", "is-cdn-ip"},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "table",
+			args:     []string{"--output", "table", "gtm", "properties"},
+			contains: []string{"ACCOUNT", "1-ABC:1-2345", "forbidden", "failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := runCommandAs(t, api, accounts, tt.args...)
+
+			if code := exitCode(err); code != tt.exitCode {
+				t.Fatalf("exit code = %d, want %d (error: %v)", code, tt.exitCode, err)
+			}
+
+			for _, s := range tt.contains {
+				if !strings.Contains(out, s) {
+					t.Errorf("output does not contain %q:\n%s", s, out)
+				}
+			}
+		})
+	}
+}
+
+func TestMultiAccountCommandsAllFailed(t *testing.T) {
+	api := newFakeAPI()
+	defer api.Close()
+
+	// Authentication failure is the most severe one
+	out, err := runCommandAs(t, api, []string{fakeBroken, fakeForbidden}, "gtm", "properties")
+	if code := exitCode(err); code != exitAuth {
+		t.Fatalf("exit code = %d, want %d (error: %v)", code, exitAuth, err)
+	}
+
+	if !strings.Contains(out, `"error": "Internal Server Error: fake API failure"`) {
+		t.Errorf("failures are not printed:\n%s", out)
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const stdinInput = "-"
+
+// flagSource provides flag values for single target. It is satisfied by
+// *cli.Context and by batch input rows which can override command flags.
+type flagSource interface {
+	String(name string) string
+	Bool(name string) bool
+	Int(name string) int
+	Duration(name string) time.Duration
+	StringSlice(name string) []string
+}
+
+// batchTarget is single row of batch input
+type batchTarget struct {
+	Target string
+	Flags  map[string]string
+}
+
+// batchRecord is the outcome of command executed for single batch target
+type batchRecord struct {
+	Target string            `json:"target"`
+	Input  map[string]string `json:"input,omitempty"`
+	Result interface{}       `json:"result,omitempty"`
+	Error  string            `json:"error,omitempty"`
+	err    error
+}
+
+// batchRecords are outcomes of command executed for every batch target in input order
+type batchRecords []batchRecord
+
+func (r batchRecords) failure() error {
+	var errs []error
+	for _, record := range r {
+		if record.err != nil {
+			errs = append(errs, record.err)
+		}
+	}
+
+	return allFailed(errs, len(r))
+}
+
+// rowFlags returns flag values from batch row, falling back to command line flags
+type rowFlags struct {
+	c   *cli.Context
+	row map[string]string
+}
+
+func (f rowFlags) String(name string) string {
+	if v, ok := f.row[name]; ok && v != "" {
+		return v
+	}
+
+	return f.c.String(name)
+}
+
+func (f rowFlags) Bool(name string) bool {
+	if v, ok := f.row[name]; ok && v != "" {
+		b, err := strconv.ParseBool(v)
+		if err == nil {
+			return b
+		}
+		log.Warnf("Ignoring '%s' value '%s' from input, it is not a boolean", name, v)
+	}
+
+	return f.c.Bool(name)
+}
+
+func (f rowFlags) Int(name string) int {
+	if v, ok := f.row[name]; ok && v != "" {
+		i, err := strconv.Atoi(v)
+		if err == nil {
+			return i
+		}
+		log.Warnf("Ignoring '%s' value '%s' from input, it is not a number", name, v)
+	}
+
+	return f.c.Int(name)
+}
+
+func (f rowFlags) Duration(name string) time.Duration {
+	if v, ok := f.row[name]; ok && v != "" {
+		d, err := time.ParseDuration(v)
+		if err == nil {
+			return d
+		}
+		log.Warnf("Ignoring '%s' value '%s' from input, it is not a duration", name, v)
+	}
+
+	return f.c.Duration(name)
+}
+
+// StringSlice reads repeatable flag, in batch row its values are separated with '|'
+func (f rowFlags) StringSlice(name string) []string {
+	if v, ok := f.row[name]; ok && v != "" {
+		return strings.Split(v, "|")
+	}
+
+	return f.c.StringSlice(name)
+}
+
+// batchFlags are shared by commands which can read their targets from file
+func batchFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "input, i",
+			Value: "",
+			Usage: "Read targets from `FILE` ('-' for stdin) instead of argument. One target per line or CSV with header where first column is the target and other columns are command flags, e.g. 'ip,hostname,query-type'",
+		},
+	}
+}
+
+// workersFlag limits concurrency of commands executed for many targets
+func workersFlag() cli.Flag {
+	return cli.IntFlag{
+		Name:  "workers",
+		Value: 5,
+		Usage: "`Number` of targets processed concurrently",
+	}
+}
+
+// runOnTargets executes fn for single argument or, when 'input' flag is
+// provided, for every target read from file producing one record per target
+func runOnTargets(c *cli.Context, errMessage string, fn func(target string, flags flagSource) (interface{}, error)) error {
+	targets, err := inputTargets(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := collectTargets(c, targets, errMessage, fn)
+	if err != nil {
+		return err
+	}
+
+	if err := printOutput(c, result); err != nil {
+		return err
+	}
+
+	return failureOf(result)
+}
+
+// inputTargets reads targets given with 'input' flag, nil means there is no input
+func inputTargets(c *cli.Context) ([]batchTarget, error) {
+	if c.String("input") == "" {
+		return nil, nil
+	}
+
+	return readBatchTargets(c.String("input"))
+}
+
+// collectTargets is runOnTargets with targets already read, which returns
+// result instead of printing it
+func collectTargets(c *cli.Context, targets []batchTarget, errMessage string, fn func(target string, flags flagSource) (interface{}, error)) (interface{}, error) {
+	if targets == nil {
+		target, err := requireArgument(c, errMessage)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := fn(target, c)
+		if err != nil {
+			return nil, newAPIError(err)
+		}
+
+		return result, nil
+	}
+
+	return runBatch(c, targets, fn), nil
+}
+
+// runBatch processes targets using at most 'workers' goroutines keeping input order
+func runBatch(c *cli.Context, targets []batchTarget, fn func(target string, flags flagSource) (interface{}, error)) batchRecords {
+	workers := c.Int("workers")
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		wg      sync.WaitGroup
+		records = make(batchRecords, len(targets))
+		queue   = make(chan int)
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for idx := range queue {
+				t := targets[idx]
+				record := batchRecord{Target: t.Target, Input: t.Flags}
+
+				result, err := fn(t.Target, rowFlags{c: c, row: t.Flags})
+				if err != nil {
+					record.err = newAPIError(err)
+					record.Error = errorText(err)
+					log.Warnf("Target %s failed: %s", t.Target, record.Error)
+				} else {
+					record.Result = result
+				}
+
+				records[idx] = record
+			}
+		}()
+	}
+
+	for idx := range targets {
+		queue <- idx
+	}
+	close(queue)
+	wg.Wait()
+
+	return records
+}
+
+// readBatchTargets reads targets from file or stdin
+func readBatchTargets(name string) ([]batchTarget, error) {
+	var r io.Reader = os.Stdin
+
+	if name != stdinInput {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, newValidationError("Cannot read input: %s", err)
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	targets, err := parseBatchTargets(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(targets) == 0 {
+		return nil, newValidationError("No targets found in input '%s'", name)
+	}
+
+	log.Debugf("Read %d targets from input '%s'", len(targets), name)
+
+	return targets, nil
+}
+
+// parseBatchTargets accepts newline-delimited targets or CSV with header
+// row. Empty lines and lines starting with '#' are ignored.
+func parseBatchTargets(r io.Reader) ([]batchTarget, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 || !strings.Contains(lines[0], ",") {
+		var targets []batchTarget
+		for _, line := range lines {
+			targets = append(targets, batchTarget{Target: line})
+		}
+
+		return targets, nil
+	}
+
+	cr := csv.NewReader(strings.NewReader(strings.Join(lines, "\n")))
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, newValidationError("Cannot parse CSV input: %s", err)
+	}
+
+	header := rows[0]
+	var targets []batchTarget
+	for _, row := range rows[1:] {
+		t := batchTarget{Target: strings.TrimSpace(row[0]), Flags: map[string]string{}}
+		for i := 1; i < len(header) && i < len(row); i++ {
+			t.Flags[strings.TrimSpace(header[i])] = strings.TrimSpace(row[i])
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, nil
+}
+
+// errorText flattens multi-line API errors into single line
+func errorText(err error) string {
+	return strings.Replace(err.Error(), "\n\t", ": ", -1)
+}
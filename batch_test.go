@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBatchTargets(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []batchTarget
+	}{
+		{
+			name:  "lines",
+			input: "# suspects from ticket\n23.15.7.10\n\n 2a02:26f0:d8::17d4:9d1 \n",
+			want:  []batchTarget{{Target: "23.15.7.10"}, {Target: "2a02:26f0:d8::17d4:9d1"}},
+		},
+		{
+			name:  "csv",
+			input: "ip,hostname,query-type\n23.15.7.10, www.example.com,AAAA\n23.15.7.11,www.example.org\n",
+			want: []batchTarget{
+				{Target: "23.15.7.10", Flags: map[string]string{"hostname": "www.example.com", "query-type": "AAAA"}},
+				{Target: "23.15.7.11", Flags: map[string]string{"hostname": "www.example.org"}},
+			},
+		},
+		{
+			name:  "empty",
+			input: "\n# nothing\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBatchTargets(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseBatchTargets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ips := filepath.Join(dir, "ips.txt")
+	ioutil.WriteFile(ips, []byte("23.15.7.10\n198.51.100.7\nnot-an-ip\n"), 0600)
+
+	invalid := filepath.Join(dir, "invalid.txt")
+	ioutil.WriteFile(invalid, []byte("not-an-ip\n23.15.7\n"), 0600)
+
+	digs := filepath.Join(dir, "digs.csv")
+	ioutil.WriteFile(digs, []byte("ip,hostname,query-type\n23.15.7.10,www.example.com,AAAA\n23.15.7.11,www.example.org,\n"), 0600)
+
+	runCommandTests(t, []commandTest{
+		{
+			name: "is-cdn-ip",
+			args: []string{"--output", "ndjson", "ip", "is-cdn-ip", "--input", ips},
+			contains: []string{
+				`{"target":"23.15.7.10","result":{"ip":"23.15.7.10","family":"IPv4","isCdnIp":true}}`,
+				`{"target":"198.51.100.7","result":{"ip":"198.51.100.7","family":"IPv4","isCdnIp":false}}`,
+				`{"target":"not-an-ip","error":"Provided IP address is not valid IPv4 or IPv6 address: not-an-ip"}`,
+			},
+		},
+		{
+			name: "dig with per row flags",
+			args: []string{"--output", "ndjson", "ip", "dig", "--input", digs},
+			contains: []string{
+				`"hostname":"www.example.com","queryType":"AAAA"`,
+				`"hostname":"www.example.org","queryType":"A"`,
+			},
+		},
+		{
+			name:     "is-cdn-ip every target failed",
+			args:     []string{"--output", "ndjson", "ip", "is-cdn-ip", "--input", invalid},
+			exitCode: exitValidation,
+			contains: []string{`{"target":"not-an-ip","error":`, `{"target":"23.15.7","error":`},
+		},
+		{
+			name:     "missing input file",
+			args:     []string{"ip", "is-cdn-ip", "--input", filepath.Join(dir, "missing.txt")},
+			exitCode: exitValidation,
+		},
+	})
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	service "github.com/apiheat/go-edgegrid/v6/service/diagnosticv2"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	cacheFileName   = "translated-errors.json"
+	cacheTTLEnvVar  = "AKAMAI_DIAGNOSTIC_TOOLS_CACHE_TTL"
+	defaultCacheTTL = 24 * time.Hour
+)
+
+// errorCacheMu serialises read-modify-write cycles of error cache between goroutines
+var errorCacheMu sync.Mutex
+
+func cmdCacheStats(c *cli.Context) error {
+	return cacheStats(c)
+}
+
+func cmdCachePurge(c *cli.Context) error {
+	return cachePurge(c)
+}
+
+func cmdCacheExport(c *cli.Context) error {
+	return cacheExport(c)
+}
+
+// cacheFlags returns flags controlling use of translated errors cache
+func cacheFlags() []cli.Flag {
+	return []cli.Flag{
+		cacheTTLFlag(),
+		cli.BoolFlag{
+			Name:  "no-cache",
+			Usage: "Always ask API instead of using previously translated error, fresh result still refreshes the cache",
+		},
+	}
+}
+
+func cacheTTLFlag() cli.Flag {
+	return cli.DurationFlag{
+		Name:   "cache-ttl",
+		Value:  defaultCacheTTL,
+		Usage:  "Use cached translations not older than `DURATION`, e.g. 30m or 72h",
+		EnvVar: cacheTTLEnvVar,
+	}
+}
+
+// cachedError is translated error kept in local cache
+type cachedError struct {
+	ErrorString string                   `json:"errorString"`
+	RequestID   string                   `json:"requestId"`
+	CachedAt    time.Time                `json:"cachedAt"`
+	Result      *service.TranslatedError `json:"result"`
+}
+
+func (e *cachedError) expired(ttl time.Duration) bool {
+	return time.Since(e.CachedAt) > ttl
+}
+
+// cachedErrors is list of cache entries presented as table with the most useful columns first
+type cachedErrors []*cachedError
+
+func (entries cachedErrors) tableHeader() []string {
+	return []string{"errorString", "requestId", "cachedAt", "httpResponseCode", "reasonForFailure"}
+}
+
+func (entries cachedErrors) tableRows() [][]string {
+	var rows [][]string
+	for _, e := range entries {
+		row := []string{e.ErrorString, e.RequestID, formatTime(e.CachedAt), "", ""}
+		if e.Result != nil {
+			row[3] = strconv.Itoa(e.Result.TranslatedError.HTTPResponseCode)
+			row[4] = e.Result.TranslatedError.ReasonForFailure
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// errorCache keeps translated errors in local state file keyed by normalized error string
+type errorCache struct {
+	path    string
+	Entries map[string]*cachedError `json:"entries"`
+}
+
+// cacheStatistics describes content of error cache
+type cacheStatistics struct {
+	Path      string `json:"path"`
+	SizeBytes int64  `json:"sizeBytes"`
+	Entries   int    `json:"entries"`
+	Fresh     int    `json:"fresh"`
+	Expired   int    `json:"expired"`
+	TTL       string `json:"ttl"`
+	Oldest    string `json:"oldest,omitempty"`
+	Newest    string `json:"newest,omitempty"`
+}
+
+func loadErrorCache() (*errorCache, error) {
+	path, err := stateFile(cacheFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &errorCache{path: path}
+	if err := readStateFile(path, cache); err != nil {
+		return nil, err
+	}
+
+	if cache.Entries == nil {
+		cache.Entries = map[string]*cachedError{}
+	}
+
+	return cache, nil
+}
+
+func (c *errorCache) save() error {
+	return writeStateFile(c.path, c)
+}
+
+// lookup returns cached translation of error string unless it is older than ttl
+func (c *errorCache) lookup(errorString string, ttl time.Duration) *cachedError {
+	entry, ok := c.Entries[errorString]
+	if !ok || entry.Result == nil || entry.expired(ttl) {
+		return nil
+	}
+
+	return entry
+}
+
+func (c *errorCache) put(errorString, requestID string, result *service.TranslatedError) {
+	c.Entries[errorString] = &cachedError{
+		ErrorString: errorString,
+		RequestID:   requestID,
+		CachedAt:    time.Now(),
+		Result:      result,
+	}
+}
+
+// sorted returns all entries, oldest first
+func (c *errorCache) sorted() cachedErrors {
+	entries := cachedErrors{}
+	for _, e := range c.Entries {
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CachedAt.Before(entries[j].CachedAt) })
+
+	return entries
+}
+
+// withErrorCache applies fn to local error cache and saves it. Like job store,
+// cache is only a convenience, so its problems never fail the command.
+func withErrorCache(fn func(cache *errorCache)) {
+	errorCacheMu.Lock()
+	defer errorCacheMu.Unlock()
+
+	cache, err := loadErrorCache()
+	if err != nil {
+		log.Warnf("Cannot load translated errors cache: %s", err)
+		return
+	}
+
+	fn(cache)
+
+	if err := cache.save(); err != nil {
+		log.Warnf("Cannot save translated errors cache: %s", err)
+	}
+}
+
+// cachedTranslation returns cached translation of error string honouring --no-cache and --cache-ttl
+func cachedTranslation(errorString string, f flagSource) *service.TranslatedError {
+	if f.Bool("no-cache") {
+		return nil
+	}
+
+	errorCacheMu.Lock()
+	defer errorCacheMu.Unlock()
+
+	cache, err := loadErrorCache()
+	if err != nil {
+		log.Warnf("Cannot load translated errors cache: %s", err)
+		return nil
+	}
+
+	entry := cache.lookup(errorString, f.Duration("cache-ttl"))
+	if entry == nil {
+		return nil
+	}
+
+	log.Infof("Using translation of '%s' cached at %s, use --no-cache to ask API again", errorString, formatTime(entry.CachedAt))
+
+	return entry.Result
+}
+
+// cacheTranslation stores translated error when error string it belongs to is known
+func cacheTranslation(errorString, requestID string, result *service.TranslatedError) {
+	if errorString == "" || result == nil {
+		return
+	}
+
+	withErrorCache(func(cache *errorCache) {
+		cache.put(errorString, requestID, result)
+	})
+}
+
+func cacheStats(c *cli.Context) error {
+	cache, err := loadErrorCache()
+	if err != nil {
+		return err
+	}
+
+	ttl := c.Duration("cache-ttl")
+	stats := cacheStatistics{Path: cache.path, Entries: len(cache.Entries), TTL: ttl.String()}
+
+	if info, err := os.Stat(cache.path); err == nil {
+		stats.SizeBytes = info.Size()
+	}
+
+	entries := cache.sorted()
+	for _, e := range entries {
+		if e.expired(ttl) {
+			stats.Expired++
+		} else {
+			stats.Fresh++
+		}
+	}
+
+	if len(entries) > 0 {
+		stats.Oldest = formatTime(entries[0].CachedAt)
+		stats.Newest = formatTime(entries[len(entries)-1].CachedAt)
+	}
+
+	return printOutput(c, stats)
+}
+
+func cachePurge(c *cli.Context) error {
+	errorCacheMu.Lock()
+	defer errorCacheMu.Unlock()
+
+	cache, err := loadErrorCache()
+	if err != nil {
+		return err
+	}
+
+	purged := 0
+	for key, e := range cache.Entries {
+		if !c.Bool("expired") || e.expired(c.Duration("cache-ttl")) {
+			delete(cache.Entries, key)
+			purged++
+		}
+	}
+
+	if err := cache.save(); err != nil {
+		return err
+	}
+
+	log.Infof("Purged %d translated errors, %d left in cache", purged, len(cache.Entries))
+
+	return nil
+}
+
+func cacheExport(c *cli.Context) error {
+	cache, err := loadErrorCache()
+	if err != nil {
+		return err
+	}
+
+	return printOutput(c, cache.sorted())
+}
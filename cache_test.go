@@ -0,0 +1,122 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func countCalls(api *fakeAPI, path string) int {
+	n := 0
+	for _, call := range api.calls() {
+		if strings.HasSuffix(call, path) {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestTranslateErrorCache(t *testing.T) {
+	api := newFakeAPI()
+	defer api.Close()
+
+	if _, err := runCommand(t, api, "cache", "purge"); err != nil {
+		t.Fatal(err)
+	}
+
+	const launch = "/errors/18.cached.1318965461.1/translate-error"
+
+	steps := []struct {
+		name     string
+		args     []string
+		launches int
+	}{
+		{"first translation", []string{"translate-error", "--no-progress", "#18.cached.1318965461.1"}, 1},
+		{"served from cache", []string{"translate-error", "--no-progress", "18.cached.1318965461.1"}, 1},
+		{"cache bypassed", []string{"translate-error", "--no-progress", "--no-cache", "18.cached.1318965461.1"}, 2},
+		{"cache expired", []string{"translate-error", "--no-progress", "--cache-ttl", "0s", "18.cached.1318965461.1"}, 3},
+	}
+
+	for _, step := range steps {
+		out, err := runCommand(t, api, step.args...)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+
+		if !strings.Contains(out, "Connection to origin server timed out") {
+			t.Errorf("%s: unexpected output:\n%s", step.name, out)
+		}
+
+		if got := countCalls(api, launch); got != step.launches {
+			t.Errorf("%s: %d launches, want %d", step.name, got, step.launches)
+		}
+	}
+
+	// Request launched by this machine is mapped to its error string and served from cache
+	const retrieve = "/translate-error-requests/req-18.cached.1318965461.1/translated-error"
+	before := countCalls(api, retrieve)
+
+	if _, err := runCommand(t, api, "translate-request", "get", "req-18.cached.1318965461.1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := countCalls(api, retrieve); got != before {
+		t.Errorf("translate-request get retrieved %d times, want cached result", got-before)
+	}
+
+	if _, err := runCommand(t, api, "translate-request", "get", "--no-cache", "req-18.cached.1318965461.1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := countCalls(api, retrieve); got != before+1 {
+		t.Errorf("translate-request get --no-cache retrieved %d times, want 1", got-before)
+	}
+}
+
+func TestCacheCommands(t *testing.T) {
+	api := newFakeAPI()
+	defer api.Close()
+
+	for _, args := range [][]string{
+		{"cache", "purge"},
+		{"translate-error", "--no-progress", "18.export.1318965461.1"},
+	} {
+		if _, err := runCommand(t, api, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runCommandTests(t, []commandTest{
+		{
+			name:     "stats",
+			args:     []string{"cache", "stats"},
+			contains: []string{`"entries": 1`, `"fresh": 1`, `"expired": 0`, `"ttl": "24h0m0s"`},
+		},
+		{
+			name:     "stats with short ttl",
+			args:     []string{"cache", "stats", "--cache-ttl", "0s"},
+			contains: []string{`"fresh": 0`, `"expired": 1`},
+		},
+		{
+			name:     "export",
+			args:     []string{"--output", "csv", "cache", "export"},
+			contains: []string{"errorString,requestId,cachedAt,httpResponseCode,reasonForFailure", "18.export.1318965461.1,req-18.export.1318965461.1,", ",503,Connection to origin server timed out"},
+		},
+		{
+			name: "purge expired keeps fresh",
+			args: []string{"cache", "purge", "--expired"},
+		},
+		{
+			name:     "still cached",
+			args:     []string{"cache", "stats"},
+			contains: []string{`"entries": 1`},
+		},
+		{
+			name: "purge",
+			args: []string{"cache", "purge"},
+		},
+		{
+			name:     "empty",
+			args:     []string{"cache", "stats"},
+			contains: []string{`"entries": 0`},
+		},
+	})
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	edgegrid "github.com/apiheat/go-edgegrid/v6/edgegrid"
+	service "github.com/apiheat/go-edgegrid/v6/service/diagnosticv2"
+)
+
+const diagnosticBasePath = "/diagnostic-tools/v2"
+
+// diagnosticClient is the part of Diagnostic Tools API used by commands.
+// It is satisfied by edgegridClient and lets tests talk to fake API.
+type diagnosticClient interface {
+	ListGhostLocations() (*service.GhostLocations, error)
+
+	LaunchTranslateErrorAsync(errorCode string) (*service.TranslateErrorAsync, error)
+	CheckTranslateErrorAsync(requestID string) (*service.TranslateErrorAsync, error)
+	RetrieveTranslateErrorAsync(requestID string) (*service.TranslatedError, error)
+
+	CheckIPAddress(ip string) (*service.CDNStatus, error)
+	RetrieveIPGeolocation(ip string) (*service.Geolocation, error)
+
+	ExecuteDig(obj, requestFrom, hostname, query string) (*service.DigResult, error)
+	ExecuteMtr(obj, requestFrom, destinationDomain string, resolveDNS bool) (*service.MtrResult, error)
+	ExecuteCurlRequest(obj, requestFrom string, request curlRequest) (*curlResult, error)
+
+	ListGTMProperties() (*service.GTMPropertiesResult, error)
+	ListGTMPropertyIPs(property, domain string) (*service.GTMPropertyIpsResult, error)
+
+	GenerateDiagnosticLink(username, testURL string) (*service.DiagnosticLinkURL, error)
+	ListDiagnosticLinkRequests() (*service.DiagnosticLinkRequests, error)
+	RetrieveDiagnosticLinkRequest(id string) (*service.DiagnosticLinkResult, error)
+}
+
+// newDiagnosticClient creates API client for given configuration
+func newDiagnosticClient(config *edgegrid.Config) diagnosticClient {
+	return &edgegridClient{service.New(config)}
+}
+
+// edgegridClient is diagnosticv2 client extended with calls the library does not cover
+type edgegridClient struct {
+	*service.Diagnosticv2
+}
+
+// curlRequest is body of curl request, unlike service.CurlRequest it carries request headers
+type curlRequest struct {
+	URL            string   `json:"url"`
+	UserAgent      string   `json:"userAgent"`
+	RequestHeaders []string `json:"requestHeaders,omitempty"`
+	Method         string   `json:"method,omitempty"`
+}
+
+// curlResult is curl response keeping all response headers, service.CurlResult knows only few of them
+type curlResult struct {
+	CurlResults struct {
+		HTTPStatusCode  int               `json:"httpStatusCode"`
+		ResponseHeaders map[string]string `json:"responseHeaders"`
+		ResponseBody    string            `json:"responseBody"`
+	} `json:"curlResults"`
+}
+
+// ExecuteCurlRequest runs curl from ghost location or IP address with given request headers
+func (dts *edgegridClient) ExecuteCurlRequest(obj, requestFrom string, request curlRequest) (*curlResult, error) {
+	resp, err := dts.Rclient.R().
+		SetBody(request).
+		SetResult(curlResult{}).
+		SetError(service.DiagnosticErrorv2{}).
+		Post(fmt.Sprintf("%s/%s/%s/curl-results", diagnosticBasePath, requestFrom, obj))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		e := resp.Error().(*service.DiagnosticErrorv2)
+		if e.Status != 0 {
+			return nil, e
+		}
+	}
+
+	return resp.Result().(*curlResult), nil
+}
+
+// rateLimitError is returned when API rejects request with 429 Too Many
+// Requests, RetryAfter is value of Retry-After header in seconds
+type rateLimitError struct {
+	RetryAfter int
+}
+
+func (e rateLimitError) Error() string {
+	return fmt.Sprintf("Too Many Requests, retry after %d seconds", e.RetryAfter)
+}
+
+// LaunchTranslateErrorAsync starts translation like the library does, but
+// reports rate limiting with Retry-After, so many launches can back off
+func (dts *edgegridClient) LaunchTranslateErrorAsync(errorCode string) (*service.TranslateErrorAsync, error) {
+	resp, err := dts.Rclient.R().
+		SetResult(service.TranslateErrorAsync{}).
+		SetError(service.DiagnosticErrorv2{}).
+		Post(fmt.Sprintf("%s/errors/%s/translate-error", diagnosticBasePath, errorCode))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() == http.StatusTooManyRequests {
+		retryAfter, _ := strconv.Atoi(resp.Header().Get("Retry-After"))
+		return nil, rateLimitError{RetryAfter: retryAfter}
+	}
+
+	if resp.IsError() {
+		e := resp.Error().(*service.DiagnosticErrorv2)
+		if e.Status != 0 {
+			return nil, e
+		}
+	}
+
+	return resp.Result().(*service.TranslateErrorAsync), nil
+}
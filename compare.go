@@ -0,0 +1,241 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+func cmdGhostCompareCurl(c *cli.Context) error {
+	return ghostCompareCurl(c)
+}
+
+func cmdGhostCompareDig(c *cli.Context) error {
+	return ghostCompareDig(c)
+}
+
+// Fields compared between locations
+var (
+	curlCompareFields = []string{"status", "contentLength", "etag", "lastModified", "cache"}
+	digCompareFields  = []string{"cnameChain", "addresses"}
+)
+
+// locationComparison is result of single location compared with the others
+type locationComparison struct {
+	Location string            `json:"location"`
+	Values   map[string]string `json:"values,omitempty"`
+	Differs  []string          `json:"differs,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// comparison tells how consistent result of the same test is across ghost
+// locations. Consensus is the most common value of every field and outliers
+// are locations which differ from it or failed.
+type comparison struct {
+	Target    string               `json:"target"`
+	Fields    []string             `json:"fields"`
+	Consensus map[string]string    `json:"consensus"`
+	Outliers  []string             `json:"outliers"`
+	Locations []locationComparison `json:"locations"`
+}
+
+func (r comparison) tableHeader() []string {
+	return append(append([]string{"location"}, r.Fields...), "outlier")
+}
+
+func (r comparison) tableRows() [][]string {
+	var rows [][]string
+	for _, l := range r.Locations {
+		row := []string{l.Location}
+		for _, field := range r.Fields {
+			row = append(row, l.Values[field])
+		}
+
+		switch {
+		case l.Error != "":
+			row = append(row, "failed: "+l.Error)
+		case len(l.Differs) > 0:
+			row = append(row, "<< "+strings.Join(l.Differs, ", "))
+		default:
+			row = append(row, "")
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// requireLocations resolves mandatory --locations flag of compare commands
+func requireLocations(c *cli.Context) ([]string, error) {
+	if c.String("locations") == "" {
+		return nil, newFlagError("Please provide --locations to compare, e.g. '*Germany*,*Japan*' or 'all'")
+	}
+
+	return resolveGhostLocations(c.String("locations"))
+}
+
+func ghostCompareCurl(c *cli.Context) error {
+	testURL, err := requireArgument(c, "Please provide URL")
+	if err != nil {
+		return err
+	}
+
+	// Pragma headers are always sent as cache status is one of compared fields
+	f := rowFlags{c: c, row: map[string]string{"url": testURL, "pragma": "true"}}
+	if err := validateCurlOptions(f); err != nil {
+		return err
+	}
+
+	locations, err := requireLocations(c)
+	if err != nil {
+		return err
+	}
+
+	report := fanOut(locations, c.Int("workers"), func(location string) (interface{}, error) {
+		return runCurl(location, requestFromGhost, f)
+	})
+
+	if err := printOutput(c, compareLocations(testURL, report, curlCompareFields, func(result interface{}) map[string]string {
+		curl := result.(curlReport)
+
+		values := map[string]string{
+			"status":        strconv.Itoa(curl.HTTPStatusCode),
+			"contentLength": curl.ResponseHeaders["Content-Length"],
+			"etag":          curl.ResponseHeaders["Etag"],
+			"lastModified":  curl.ResponseHeaders["Last-Modified"],
+		}
+		// Cache outcome is compared, as TCP_HIT and TCP_MEM_HIT are the same for users
+		if curl.Akamai != nil {
+			values["cache"] = curl.Akamai.Cache
+		}
+
+		return values
+	})); err != nil {
+		return err
+	}
+
+	return report.failure()
+}
+
+func ghostCompareDig(c *cli.Context) error {
+	hostname, err := requireArgument(c, "Please provide HOSTNAME")
+	if err != nil {
+		return err
+	}
+
+	f := rowFlags{c: c, row: map[string]string{"hostname": hostname}}
+	if err := validateDigOptions(f); err != nil {
+		return err
+	}
+
+	locations, err := requireLocations(c)
+	if err != nil {
+		return err
+	}
+
+	report := fanOut(locations, c.Int("workers"), func(location string) (interface{}, error) {
+		response, err := apiClient.ExecuteDig(location, requestFromGhost, hostname, f.String("query-type"))
+		if err != nil {
+			return nil, err
+		}
+
+		return newDigReport(response), nil
+	})
+
+	if err := printOutput(c, compareLocations(hostname, report, digCompareFields, func(result interface{}) map[string]string {
+		dig := result.(digReport)
+
+		var chain []string
+		for _, rr := range dig.Answer {
+			if rr.Type == "CNAME" {
+				chain = append(chain, rr.Data)
+			}
+		}
+
+		addresses := dig.addresses()
+
+		// Order of address records is rotated by name servers, so it does not matter
+		sort.Strings(addresses)
+
+		return map[string]string{
+			"cnameChain": strings.Join(chain, " -> "),
+			"addresses":  strings.Join(addresses, ", "),
+		}
+	})); err != nil {
+		return err
+	}
+
+	return report.failure()
+}
+
+// compareLocations finds the most common value of every field across
+// locations and marks locations which differ from it
+func compareLocations(target string, report locationReport, fields []string, values func(result interface{}) map[string]string) comparison {
+	result := comparison{
+		Target:    target,
+		Fields:    fields,
+		Consensus: map[string]string{},
+		Outliers:  []string{},
+	}
+
+	counts := map[string]map[string]int{}
+	for _, field := range fields {
+		counts[field] = map[string]int{}
+	}
+
+	for _, location := range report.locations() {
+		res := report[location]
+
+		l := locationComparison{Location: location, Error: res.Error}
+		if res.Error == "" {
+			l.Values = values(res.Result)
+			for _, field := range fields {
+				counts[field][l.Values[field]]++
+			}
+		}
+
+		result.Locations = append(result.Locations, l)
+	}
+
+	for _, field := range fields {
+		result.Consensus[field] = mostCommon(counts[field])
+	}
+
+	for i := range result.Locations {
+		l := &result.Locations[i]
+
+		for _, field := range fields {
+			if l.Error == "" && l.Values[field] != result.Consensus[field] {
+				l.Differs = append(l.Differs, field)
+			}
+		}
+
+		if l.Error != "" || len(l.Differs) > 0 {
+			result.Outliers = append(result.Outliers, l.Location)
+		}
+	}
+
+	if len(result.Outliers) > 0 {
+		log.Warnf("%d of %d locations differ from the rest: %s", len(result.Outliers), len(result.Locations), strings.Join(result.Outliers, ", "))
+	} else {
+		log.Infof("All %d locations returned the same result", len(result.Locations))
+	}
+
+	return result
+}
+
+// mostCommon returns value with the highest count, ties are broken alphabetically
+func mostCommon(counts map[string]int) string {
+	best, bestCount := "", 0
+	for value, count := range counts {
+		if count > bestCount || (count == bestCount && value < best) {
+			best, bestCount = value, count
+		}
+	}
+
+	return best
+}
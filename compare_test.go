@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+func TestMostCommon(t *testing.T) {
+	tests := []struct {
+		counts map[string]int
+		want   string
+	}{
+		{map[string]int{"200": 3, "404": 1}, "200"},
+		{map[string]int{"b": 2, "a": 2}, "a"},
+		{map[string]int{}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := mostCommon(tt.counts); got != tt.want {
+			t.Errorf("mostCommon(%v) = %q, want %q", tt.counts, got, tt.want)
+		}
+	}
+}
+
+func TestGhostCompare(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name: "curl outlier",
+			args: []string{"ghost", "compare-curl", "--locations", "all", "https://www.example.com/"},
+			contains: []string{
+				`"etag": "\"v2\""`,
+				`"cache": "miss"`,
+				`"outliers": [
+        "tokyo-japan"
+    ]`,
+				`"differs": [
+                "etag"
+            ]`,
+			},
+		},
+		{
+			name:     "curl table",
+			args:     []string{"--output", "table", "ghost", "compare-curl", "--locations", "*germany*,tokyo-japan", "https://www.example.com/"},
+			contains: []string{"LOCATION", "ETAG", "OUTLIER", "<< etag"},
+		},
+		{
+			name:     "curl failed location",
+			args:     []string{"ghost", "compare-curl", "--locations", "paris-france", "https://" + fakeBroken + ".example.com/"},
+			exitCode: exitAPI,
+			contains: []string{`"error": "Internal Server Error: fake API failure"`, `"location": "paris-france"`},
+		},
+		{
+			name:     "curl without locations",
+			args:     []string{"ghost", "compare-curl", "https://www.example.com/"},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "curl without url",
+			args:     []string{"ghost", "compare-curl", "--locations", "all"},
+			exitCode: exitValidation,
+		},
+		{
+			name: "dig outlier",
+			args: []string{"ghost", "compare-dig", "--locations", "all", "www.example.com"},
+			contains: []string{`"cnameChain": "www.example.com.edgekey.net."`, `"cnameChain": "www.example.com.edgesuite.net."`, `"differs": [
+                "cnameChain"
+            ]`},
+		},
+		{
+			name:     "dig invalid query type",
+			args:     []string{"ghost", "compare-dig", "--locations", "all", "--query-type", "TXT", "www.example.com"},
+			exitCode: exitInvalidQueryType,
+		},
+	})
+}
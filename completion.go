@@ -0,0 +1,319 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+func cmdCompletion(c *cli.Context) error {
+	return printCompletionScript(c)
+}
+
+const (
+	completionFileName = "completion.json"
+	completionCacheTTL = 10 * time.Minute
+)
+
+// Kinds of values kept in completion cache
+const (
+	completeGTMDomains      = "gtm-domains"
+	completeGTMProperties   = "gtm-properties"
+	completeDiagnosticLinks = "diagnostic-links"
+)
+
+// completionCacheMu serialises read-modify-write cycles of completion cache
+var completionCacheMu sync.Mutex
+
+// completionValues are values of single kind together with time they were listed
+type completionValues struct {
+	FetchedAt time.Time `json:"fetchedAt"`
+	Values    []string  `json:"values"`
+}
+
+// completionCache keeps values listed from API for a short time, so that
+// completion does not wait for API on every key press
+type completionCache struct {
+	Kinds map[string]completionValues `json:"kinds"`
+}
+
+// completionScripts are templates of completion scripts per shell. Every
+// script asks the tool itself for candidates with --generate-bash-completion
+// flag, so commands, flags and values are always in sync with the binary.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for {{.Program}}, load it with: source <({{.Program}} completion bash)
+{{.Function}}() {
+  local cur opts
+  COMPREPLY=()
+  cur="${COMP_WORDS[COMP_CWORD]}"
+  if [[ "$cur" == "-"* ]]; then
+    opts=$( "${COMP_WORDS[@]:0:$COMP_CWORD}" "$cur" --generate-bash-completion 2>/dev/null )
+  else
+    opts=$( "${COMP_WORDS[@]:0:$COMP_CWORD}" --generate-bash-completion 2>/dev/null )
+  fi
+  COMPREPLY=( $(compgen -W "${opts}" -- "${cur}") )
+  return 0
+}
+
+complete -o bashdefault -o default -F {{.Function}} {{.Program}}
+`,
+	"zsh": `#compdef {{.Program}}
+# zsh completion for {{.Program}}, load it with: source <({{.Program}} completion zsh)
+{{.Function}}() {
+  local -a opts
+  local cur="${words[CURRENT]}"
+  if [[ "$cur" == -* ]]; then
+    opts=("${(@f)$(${words[@]:0:$((CURRENT-1))} "$cur" --generate-bash-completion 2>/dev/null)}")
+  else
+    opts=("${(@f)$(${words[@]:0:$((CURRENT-1))} --generate-bash-completion 2>/dev/null)}")
+  fi
+  compadd -a opts
+}
+
+compdef {{.Function}} {{.Program}}
+`,
+	"fish": `# fish completion for {{.Program}}, load it with: {{.Program}} completion fish | source
+function {{.Function}}
+    set -l args (commandline -opc)
+    set -l cur (commandline -ct)
+    if string match -q -- '-*' $cur
+        $args $cur --generate-bash-completion 2>/dev/null
+    else
+        $args --generate-bash-completion 2>/dev/null
+    end
+end
+
+complete -c {{.Program}} -f -a '({{.Function}})'
+`,
+}
+
+func completionShells() []string {
+	var shells []string
+	for shell := range completionScripts {
+		shells = append(shells, shell)
+	}
+	sort.Strings(shells)
+
+	return shells
+}
+
+func printCompletionScript(c *cli.Context) error {
+	shell, err := requireArgument(c, fmt.Sprintf("Please provide SHELL, one of: %s", strings.Join(completionShells(), ", ")))
+	if err != nil {
+		return err
+	}
+
+	script, ok := completionScripts[shell]
+	if !ok {
+		return newValidationError("Unsupported shell '%s', use one of: %s", shell, strings.Join(completionShells(), ", "))
+	}
+
+	program := c.String("name")
+	if program == "" {
+		program = filepath.Base(os.Args[0])
+	}
+
+	t := template.Must(template.New(shell).Parse(script))
+
+	return t.Execute(outputWriter, map[string]string{
+		"Program":  program,
+		"Function": "_" + regexp.MustCompile(`\W`).ReplaceAllString(program, "_") + "_complete",
+	})
+}
+
+// completing tells whether application was invoked by shell to complete command line
+func completing(args []string) bool {
+	return len(args) > 0 && args[len(args)-1] == "--"+cli.BashCompletionFlag.GetName()
+}
+
+// completeWith builds completion of command which suggests values for its
+// arguments and for flags listed in flagValues. Flags themselves are
+// suggested as usual when word being completed starts with dash.
+func completeWith(values func() []string, flagValues map[string]func() []string) cli.BashCompleteFunc {
+	return func(c *cli.Context) {
+		var lastArg string
+		if len(os.Args) > 2 {
+			lastArg = os.Args[len(os.Args)-2]
+		}
+
+		if complete, ok := flagValues[strings.TrimLeft(lastArg, "-")]; ok && strings.HasPrefix(lastArg, "-") {
+			printCompletions(c, complete())
+			return
+		}
+
+		if strings.HasPrefix(lastArg, "-") || values == nil {
+			cli.DefaultCompleteWithFlags(&c.Command)(c)
+			return
+		}
+
+		printCompletions(c, values())
+	}
+}
+
+func printCompletions(c *cli.Context, values []string) {
+	for _, v := range values {
+		fmt.Fprintln(c.App.Writer, v)
+	}
+}
+
+// argumentValue returns value of flag given on command line being completed.
+// Completed command line is often incomplete, so it is not parsed as flags.
+func argumentValue(args []string, name string) string {
+	for i, arg := range args {
+		switch {
+		case arg == "--"+name && i+1 < len(args):
+			return args[i+1]
+		case strings.HasPrefix(arg, "--"+name+"="):
+			return strings.TrimPrefix(arg, "--"+name+"=")
+		}
+	}
+
+	return ""
+}
+
+func readCompletionCache() (*completionCache, string, error) {
+	path, err := stateFile(completionFileName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cache := &completionCache{}
+	if err := readStateFile(path, cache); err != nil {
+		return nil, "", err
+	}
+
+	if cache.Kinds == nil {
+		cache.Kinds = map[string]completionValues{}
+	}
+
+	return cache, path, nil
+}
+
+// rememberCompletions stores values listed from API for later completion
+func rememberCompletions(kinds map[string][]string) {
+	completionCacheMu.Lock()
+	defer completionCacheMu.Unlock()
+
+	cache, path, err := readCompletionCache()
+	if err == nil {
+		for kind, values := range kinds {
+			sort.Strings(values)
+			cache.Kinds[kind] = completionValues{FetchedAt: time.Now(), Values: values}
+		}
+		err = writeStateFile(path, cache)
+	}
+
+	if err != nil {
+		log.Warnf("Cannot save completion cache: %s", err)
+	}
+}
+
+// completionValuesOf returns cached values of given kind. When they are
+// missing or older than completionCacheTTL they are listed from API again,
+// provided that credentials could be loaded.
+func completionValuesOf(kind string, refresh func() error) []string {
+	cache, _, err := readCompletionCache()
+	if err != nil {
+		return nil
+	}
+
+	cached, ok := cache.Kinds[kind]
+	if (ok && time.Since(cached.FetchedAt) < completionCacheTTL) || apiClient == nil {
+		return cached.Values
+	}
+
+	if err := refresh(); err != nil {
+		return cached.Values
+	}
+
+	if cache, _, err = readCompletionCache(); err != nil {
+		return nil
+	}
+
+	return cache.Kinds[kind].Values
+}
+
+// completeGhostLocations completes ghost location argument and --locations flag
+func completeGhostLocations() cli.BashCompleteFunc {
+	return completeWith(ghostLocationIDs, map[string]func() []string{"locations": ghostLocationIDs})
+}
+
+// ghostLocationIDs completes ghost locations from locations cache
+func ghostLocationIDs() []string {
+	var locations []ghostLocation
+	if apiClient != nil {
+		locations, _, _ = knownGhostLocations(false)
+	} else if cache, err := readLocationsCache(); err == nil {
+		locations = cache.Locations
+	}
+
+	var ids []string
+	for _, l := range locations {
+		ids = append(ids, l.ID)
+	}
+
+	return ids
+}
+
+func gtmDomains() []string {
+	return completionValuesOf(completeGTMDomains, refreshGTMProperties)
+}
+
+// gtmPropertyNames completes properties of domain given with --domain, or of all domains
+func gtmPropertyNames() []string {
+	properties := completionValuesOf(completeGTMProperties, refreshGTMProperties)
+
+	domain := argumentValue(os.Args, "domain")
+
+	var names []string
+	for _, p := range properties {
+		parts := strings.SplitN(p, "@", 2)
+		if len(parts) == 2 && (domain == "" || parts[1] == domain) {
+			names = append(names, parts[0])
+		}
+	}
+
+	return names
+}
+
+func refreshGTMProperties() error {
+	_, err := fetchGTMProperties(apiClient)
+	return err
+}
+
+func diagnosticLinkIDs() []string {
+	return completionValuesOf(completeDiagnosticLinks, func() error {
+		_, err := fetchDiagnosticLinkRequests(apiClient)
+		return err
+	})
+}
+
+// unfetchedRequestIDs completes translate error requests which were not retrieved yet
+func unfetchedRequestIDs() []string {
+	store, err := loadJobStore()
+	if err != nil {
+		return nil
+	}
+
+	var ids []string
+	for _, job := range store.unfetched() {
+		ids = append(ids, job.RequestID)
+	}
+
+	return ids
+}
+
+// diagnosticLinkID formats numeric request ID of diagnostic link
+func diagnosticLinkID(id uint32) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
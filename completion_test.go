@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// completeCommand runs application the way shell does when completing
+// command line and returns suggested candidates
+func completeCommand(t *testing.T, api *fakeAPI, args ...string) []string {
+	t.Helper()
+
+	args = append(append([]string{"akamai-cli-diagnostic-tools"}, args...), "--generate-bash-completion")
+
+	osArgs := os.Args
+	os.Args = args
+	defer func() { os.Args = osArgs }()
+
+	apiClient = api.client()
+
+	var out bytes.Buffer
+	app := newApp()
+	app.Before = nil
+	app.Writer = &out
+	app.ErrWriter = ioutil.Discard
+
+	if err := app.Run(args); err != nil {
+		t.Fatalf("completion of %v failed: %s", args, err)
+	}
+
+	return strings.Fields(out.String())
+}
+
+func TestCompletionScripts(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name:     "bash",
+			args:     []string{"completion", "--name", "akamai-cli-diagnostic-tools", "bash"},
+			contains: []string{"complete -o bashdefault -o default -F _akamai_cli_diagnostic_tools_complete akamai-cli-diagnostic-tools"},
+		},
+		{
+			name:     "zsh",
+			args:     []string{"completion", "--name", "akamai-cli-diagnostic-tools", "zsh"},
+			contains: []string{"#compdef akamai-cli-diagnostic-tools", "--generate-bash-completion"},
+		},
+		{
+			name:     "fish",
+			args:     []string{"completion", "--name", "akamai-cli-diagnostic-tools", "fish"},
+			contains: []string{"complete -c akamai-cli-diagnostic-tools -f -a '(_akamai_cli_diagnostic_tools_complete)'"},
+		},
+		{
+			name:     "without shell",
+			args:     []string{"completion"},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "unsupported shell",
+			args:     []string{"completion", "powershell"},
+			exitCode: exitValidation,
+		},
+	})
+}
+
+func TestCompletionValues(t *testing.T) {
+	api := newFakeAPI()
+	defer api.Close()
+
+	if _, err := runCommand(t, api, "translate-request", "launch", "#18.6f64d440.1318965461.completion"); err != nil {
+		t.Fatalf("launch failed: %s", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"commands", []string{}, []string{"completion", "ghost", "gtm", "translate-error"}},
+		{"shells", []string{"completion"}, []string{"bash", "fish", "zsh"}},
+		{"ghost locations", []string{"ghost", "dig", "--hostname", "www.example.com"}, []string{"frankfurt-germany", "tokyo-japan"}},
+		{"ghost locations flag", []string{"ghost", "compare-dig", "--locations"}, []string{"paris-france"}},
+		{"ghost flags", []string{"ghost", "curl", "--he"}, []string{"--header", "--headers-only"}},
+		{"gtm domains", []string{"gtm", "ip-addresses", "--domain"}, []string{"example.akadns.net"}},
+		{"gtm properties", []string{"gtm", "ip-addresses", "--domain", "example.akadns.net"}, []string{"api", "www"}},
+		{"diagnostic links", []string{"diagnostic-link", "get"}, []string{"1234"}},
+		{"translate requests", []string{"translate-request", "check"}, []string{"req-18.6f64d440.1318965461.completion"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := completeCommand(t, api, tt.args...)
+
+			for _, want := range tt.want {
+				if !containsString(got, want) {
+					t.Errorf("completion of %v = %v, missing %q", tt.args, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCompletionCache(t *testing.T) {
+	api := newFakeAPI()
+	defer api.Close()
+
+	path, _ := stateFile(completionFileName)
+	os.Remove(path)
+
+	completeCommand(t, api, "gtm", "ip-addresses", "--domain")
+	completeCommand(t, api, "gtm", "ip-addresses", "--domain", "example.akadns.net")
+
+	if n := countCalls(api, "gtm/gtm-properties"); n != 1 {
+		t.Errorf("GTM properties were listed %d times, want 1", n)
+	}
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
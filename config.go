@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	common "github.com/apiheat/akamai-cli-common"
+	edgegrid "github.com/apiheat/go-edgegrid/v6/edgegrid"
+	"github.com/go-ini/ini"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+func cmdConfigListSections(c *cli.Context) error {
+	return configListSections(c)
+}
+
+func cmdConfigVerify(c *cli.Context) error {
+	return configVerify(c)
+}
+
+func cmdConfigShow(c *cli.Context) error {
+	return configShow(c)
+}
+
+// Fields every edgerc section needs for API calls
+var credentialFields = []string{"host", "client_token", "client_secret", "access_token"}
+
+// Statuses of verified edgerc sections
+const (
+	sectionOK           = "ok"
+	sectionIncomplete   = "incomplete"
+	sectionUnauthorized = "unauthorized"
+	sectionFailed       = "failed"
+)
+
+// clientForCredentials creates API client used to verify edgerc section
+var clientForCredentials = newAPIClient
+
+// edgercSection is single section of edgerc file
+type edgercSection struct {
+	Section  string   `json:"section"`
+	Host     string   `json:"host"`
+	Selected bool     `json:"selected"`
+	Missing  []string `json:"missing,omitempty"`
+}
+
+type edgercSections []edgercSection
+
+func (sections edgercSections) tableHeader() []string {
+	return []string{"section", "host", "selected", "missing"}
+}
+
+func (sections edgercSections) tableRows() [][]string {
+	var rows [][]string
+	for _, s := range sections {
+		selected := ""
+		if s.Selected {
+			selected = "*"
+		}
+
+		rows = append(rows, []string{s.Section, s.Host, selected, strings.Join(s.Missing, ", ")})
+	}
+
+	return rows
+}
+
+// sectionVerification is result of checking single edgerc section against API
+type sectionVerification struct {
+	Section string   `json:"section"`
+	Host    string   `json:"host"`
+	Status  string   `json:"status"`
+	Missing []string `json:"missing,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+type sectionVerifications []sectionVerification
+
+func (results sectionVerifications) tableHeader() []string {
+	return []string{"section", "host", "status", "error"}
+}
+
+func (results sectionVerifications) tableRows() [][]string {
+	var rows [][]string
+	for _, r := range results {
+		errorText := r.Error
+		if len(r.Missing) > 0 {
+			errorText = "missing " + strings.Join(r.Missing, ", ")
+		}
+
+		rows = append(rows, []string{r.Section, r.Host, r.Status, errorText})
+	}
+
+	return rows
+}
+
+// resolvedCredentials are credentials the tool would use, with secrets redacted
+type resolvedCredentials struct {
+	Source            string   `json:"source"`
+	Host              string   `json:"host"`
+	ClientToken       string   `json:"clientToken"`
+	ClientSecret      string   `json:"clientSecret"`
+	AccessToken       string   `json:"accessToken"`
+	AccountSwitchKeys []string `json:"accountSwitchKeys,omitempty"`
+}
+
+// readEdgercSections lists sections of edgerc file selected with --config
+func readEdgercSections(c *cli.Context) (edgercSections, error) {
+	file := c.GlobalString("config")
+
+	edgerc, err := ini.Load(file)
+	if err != nil {
+		return nil, newValidationError("Cannot read credentials file: %s", err)
+	}
+
+	sections := edgercSections{}
+	for _, s := range edgerc.Sections() {
+		// Keys outside of any section are not credentials
+		if s.Name() == ini.DEFAULT_SECTION && len(s.Keys()) == 0 {
+			continue
+		}
+
+		section := edgercSection{
+			Section:  s.Name(),
+			Host:     s.Key("host").String(),
+			Selected: s.Name() == c.GlobalString("section"),
+		}
+
+		for _, field := range credentialFields {
+			if strings.TrimSpace(s.Key(field).String()) == "" {
+				section.Missing = append(section.Missing, field)
+			}
+		}
+
+		sections = append(sections, section)
+	}
+
+	if len(sections) == 0 {
+		return nil, newValidationError("No sections found in credentials file %s", file)
+	}
+
+	return sections, nil
+}
+
+func configListSections(c *cli.Context) error {
+	sections, err := readEdgercSections(c)
+	if err != nil {
+		return err
+	}
+
+	return printOutput(c, sections)
+}
+
+// configVerify checks that sections have all credential fields and that
+// API accepts them for Diagnostic Tools by listing ghost locations
+func configVerify(c *cli.Context) error {
+	sections, err := readEdgercSections(c)
+	if err != nil {
+		return err
+	}
+
+	keys, err := parseAccountSwitchKeys(c.GlobalString("ask"))
+	if err != nil {
+		return err
+	}
+
+	key := ""
+	if len(keys) > 0 {
+		key = keys[0]
+	}
+
+	var results sectionVerifications
+	for _, s := range sections {
+		if c.NArg() > 0 && !common.IsStringInSlice(s.Section, c.Args()) {
+			continue
+		}
+
+		results = append(results, verifySection(c, s, key))
+	}
+
+	if len(results) == 0 {
+		return newValidationError("Sections %s not found in credentials file %s", strings.Join(c.Args(), ", "), c.GlobalString("config"))
+	}
+
+	if err := printOutput(c, results); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Status != sectionOK {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return authError{err: fmt.Errorf("%d of %d sections failed verification", failed, len(results))}
+	}
+
+	return nil
+}
+
+func verifySection(c *cli.Context, s edgercSection, accountSwitchKey string) sectionVerification {
+	result := sectionVerification{Section: s.Section, Host: s.Host, Missing: s.Missing}
+
+	if len(s.Missing) > 0 {
+		result.Status = sectionIncomplete
+		return result
+	}
+
+	creds, err := edgegrid.NewCredentials().FromFile(c.GlobalString("config")).Section(s.Section)
+	if err != nil {
+		result.Status, result.Error = sectionIncomplete, err.Error()
+		return result
+	}
+
+	log.Debugf("Verifying section '%s' with host %s", s.Section, s.Host)
+
+	_, err = clientForCredentials(c, creds, accountSwitchKey).ListGhostLocations()
+	switch err := newAPIError(err).(type) {
+	case nil:
+		result.Status = sectionOK
+	case authError:
+		result.Status = sectionUnauthorized
+		result.Error = fmt.Sprintf("Credentials were rejected or API client lacks Diagnostic Tools scope: %s", errorText(err))
+	default:
+		result.Status, result.Error = sectionFailed, errorText(err)
+	}
+
+	return result
+}
+
+func configShow(c *cli.Context) error {
+	creds, source, err := loadCredentials(c)
+	if err != nil {
+		return err
+	}
+
+	keys, err := parseAccountSwitchKeys(c.GlobalString("ask"))
+	if err != nil {
+		return err
+	}
+
+	return printOutput(c, resolvedCredentials{
+		Source:            source,
+		Host:              creds.Host,
+		ClientToken:       redact(creds.ClientToken, 4),
+		ClientSecret:      redact(creds.ClientSecret, 0),
+		AccessToken:       redact(creds.AccessToken, 4),
+		AccountSwitchKeys: keys,
+	})
+}
+
+// redact hides secret value keeping at most visible characters at both ends,
+// which is enough to tell tokens apart
+func redact(secret string, visible int) string {
+	if len(secret) <= 4*visible || visible == 0 {
+		return "****"
+	}
+
+	return secret[:visible] + "****" + secret[len(secret)-visible:]
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	edgegrid "github.com/apiheat/go-edgegrid/v6/edgegrid"
+	"github.com/urfave/cli"
+)
+
+const testEdgerc = `[default]
+client_secret = very-secret-value
+host = akab-default.luna.akamaiapis.net
+access_token = akab-access-token-1234
+client_token = akab-client-token-5678
+
+[` + fakeForbidden + `]
+client_secret = other-secret
+host = ` + fakeForbidden + `.luna.akamaiapis.net
+access_token = akab-access
+client_token = akab-client
+
+[partial]
+host = akab-partial.luna.akamaiapis.net
+client_token = akab-client
+`
+
+func TestConfigCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "edgerc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	edgerc := filepath.Join(dir, ".edgerc")
+	ioutil.WriteFile(edgerc, []byte(testEdgerc), 0600)
+
+	api := newFakeAPI()
+	defer api.Close()
+
+	// Sections are verified against fake API, hosts starting with failure marker fail
+	clientForCredentials = func(c *cli.Context, creds *edgegrid.Credentials, accountSwitchKey string) diagnosticClient {
+		return api.clientFor(strings.Split(creds.Host, ".")[0])
+	}
+	defer func() { clientForCredentials = newAPIClient }()
+
+	runCommandTests(t, []commandTest{
+		{
+			name:     "list sections",
+			args:     []string{"--config", edgerc, "config", "list-sections"},
+			contains: []string{`"section": "default"`, `"selected": true`, `"host": "akab-partial.luna.akamaiapis.net"`, `"client_secret"`, `"access_token"`},
+		},
+		{
+			name:     "list sections of missing file",
+			args:     []string{"--config", filepath.Join(dir, "missing"), "config", "list-sections"},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "verify section",
+			args:     []string{"--config", edgerc, "config", "verify", "default"},
+			contains: []string{`"status": "ok"`},
+		},
+		{
+			name:     "verify all sections",
+			args:     []string{"--config", edgerc, "--output", "table", "config", "verify"},
+			exitCode: exitAuth,
+			contains: []string{"unauthorized", "Diagnostic Tools scope", "incomplete", "missing client_secret, access_token"},
+		},
+		{
+			name:     "verify unknown section",
+			args:     []string{"--config", edgerc, "config", "verify", "staging"},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "show",
+			args:     []string{"--config", edgerc, "--ask", "1-ABC:1-2345", "config", "show"},
+			contains: []string{`"source": "section 'default' of ` + edgerc, `"host": "akab-default.luna.akamaiapis.net"`, `"clientSecret": "****"`, `"accessToken": "akab****1234"`, `"1-ABC:1-2345"`},
+		},
+		{
+			name:     "show unknown section",
+			args:     []string{"--config", edgerc, "--section", "staging", "config", "show"},
+			exitCode: exitAuth,
+		},
+	})
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		secret  string
+		visible int
+		want    string
+	}{
+		{"akab-client-token-5678", 4, "akab****5678"},
+		{"short", 4, "****"},
+		{"very-secret-value", 0, "****"},
+	}
+
+	for _, tt := range tests {
+		if got := redact(tt.secret, tt.visible); got != tt.want {
+			t.Errorf("redact(%q, %d) = %q, want %q", tt.secret, tt.visible, got, tt.want)
+		}
+	}
+}
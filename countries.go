@@ -0,0 +1,200 @@
+package main
+
+import (
+	"math"
+	"strings"
+)
+
+// country is country as used in ghost location names together with its ISO code and continent
+type country struct {
+	Code      string
+	Name      string
+	Continent string
+}
+
+// countries known to host ghost locations, names follow ghost location names
+var countries = []country{
+	{"AE", "United Arab Emirates", "AS"},
+	{"AR", "Argentina", "SA"},
+	{"AT", "Austria", "EU"},
+	{"AU", "Australia", "OC"},
+	{"BE", "Belgium", "EU"},
+	{"BG", "Bulgaria", "EU"},
+	{"BR", "Brazil", "SA"},
+	{"CA", "Canada", "NA"},
+	{"CH", "Switzerland", "EU"},
+	{"CL", "Chile", "SA"},
+	{"CN", "China", "AS"},
+	{"CO", "Colombia", "SA"},
+	{"CZ", "Czech Republic", "EU"},
+	{"DE", "Germany", "EU"},
+	{"DK", "Denmark", "EU"},
+	{"EG", "Egypt", "AF"},
+	{"ES", "Spain", "EU"},
+	{"FI", "Finland", "EU"},
+	{"FR", "France", "EU"},
+	{"GB", "United Kingdom", "EU"},
+	{"GR", "Greece", "EU"},
+	{"HK", "Hong Kong", "AS"},
+	{"HU", "Hungary", "EU"},
+	{"ID", "Indonesia", "AS"},
+	{"IE", "Ireland", "EU"},
+	{"IL", "Israel", "AS"},
+	{"IN", "India", "AS"},
+	{"IT", "Italy", "EU"},
+	{"JP", "Japan", "AS"},
+	{"KE", "Kenya", "AF"},
+	{"KR", "South Korea", "AS"},
+	{"MX", "Mexico", "NA"},
+	{"MY", "Malaysia", "AS"},
+	{"NG", "Nigeria", "AF"},
+	{"NL", "Netherlands", "EU"},
+	{"NO", "Norway", "EU"},
+	{"NZ", "New Zealand", "OC"},
+	{"PE", "Peru", "SA"},
+	{"PH", "Philippines", "AS"},
+	{"PL", "Poland", "EU"},
+	{"PT", "Portugal", "EU"},
+	{"RO", "Romania", "EU"},
+	{"RU", "Russia", "EU"},
+	{"SA", "Saudi Arabia", "AS"},
+	{"SE", "Sweden", "EU"},
+	{"SG", "Singapore", "AS"},
+	{"TH", "Thailand", "AS"},
+	{"TR", "Turkey", "AS"},
+	{"TW", "Taiwan", "AS"},
+	{"UA", "Ukraine", "EU"},
+	{"US", "United States", "NA"},
+	{"VN", "Vietnam", "AS"},
+	{"ZA", "South Africa", "AF"},
+}
+
+// coordinates are latitude and longitude in degrees
+type coordinates struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// cityCoordinates of cities hosting ghost locations, keyed by country code
+// and lower case city as used in ghost location names. Locations in cities
+// missing here are matched by country or continent only.
+var cityCoordinates = map[string]coordinates{
+	"AE/dubai":            {25.20, 55.27},
+	"AR/buenos aires":     {-34.60, -58.38},
+	"AT/vienna":           {48.21, 16.37},
+	"AU/sydney":           {-33.87, 151.21},
+	"AU/melbourne":        {-37.81, 144.96},
+	"AU/brisbane":         {-27.47, 153.03},
+	"AU/perth":            {-31.95, 115.86},
+	"BE/brussels":         {50.85, 4.35},
+	"BG/sofia":            {42.70, 23.32},
+	"BR/sao paulo":        {-23.55, -46.63},
+	"BR/rio de janeiro":   {-22.91, -43.17},
+	"CA/toronto":          {43.65, -79.38},
+	"CA/montreal":         {45.50, -73.57},
+	"CA/vancouver":        {49.28, -123.12},
+	"CH/zurich":           {47.38, 8.54},
+	"CH/geneva":           {46.20, 6.14},
+	"CL/santiago":         {-33.45, -70.67},
+	"CN/beijing":          {39.90, 116.41},
+	"CN/shanghai":         {31.23, 121.47},
+	"CO/bogota":           {4.71, -74.07},
+	"CZ/prague":           {50.08, 14.44},
+	"DE/berlin":           {52.52, 13.40},
+	"DE/frankfurt":        {50.11, 8.68},
+	"DE/munich":           {48.14, 11.58},
+	"DE/hamburg":          {53.55, 9.99},
+	"DE/dusseldorf":       {51.23, 6.77},
+	"DK/copenhagen":       {55.68, 12.57},
+	"EG/cairo":            {30.04, 31.24},
+	"ES/madrid":           {40.42, -3.70},
+	"ES/barcelona":        {41.39, 2.17},
+	"FI/helsinki":         {60.17, 24.94},
+	"FR/paris":            {48.86, 2.35},
+	"FR/marseille":        {43.30, 5.37},
+	"GB/london":           {51.51, -0.13},
+	"GB/manchester":       {53.48, -2.24},
+	"GR/athens":           {37.98, 23.73},
+	"HK/hong kong":        {22.32, 114.17},
+	"HU/budapest":         {47.50, 19.04},
+	"ID/jakarta":          {-6.21, 106.85},
+	"IE/dublin":           {53.35, -6.26},
+	"IL/tel aviv":         {32.09, 34.78},
+	"IN/mumbai":           {19.08, 72.88},
+	"IN/chennai":          {13.08, 80.27},
+	"IN/new delhi":        {28.61, 77.21},
+	"IN/bangalore":        {12.97, 77.59},
+	"IT/milan":            {45.46, 9.19},
+	"IT/rome":             {41.90, 12.50},
+	"JP/tokyo":            {35.68, 139.69},
+	"JP/osaka":            {34.69, 135.50},
+	"KE/nairobi":          {-1.29, 36.82},
+	"KR/seoul":            {37.57, 126.98},
+	"MX/mexico city":      {19.43, -99.13},
+	"MY/kuala lumpur":     {3.14, 101.69},
+	"NG/lagos":            {6.52, 3.38},
+	"NL/amsterdam":        {52.37, 4.90},
+	"NO/oslo":             {59.91, 10.75},
+	"NZ/auckland":         {-36.85, 174.76},
+	"PE/lima":             {-12.05, -77.04},
+	"PH/manila":           {14.60, 120.98},
+	"PL/warsaw":           {52.23, 21.01},
+	"PT/lisbon":           {38.72, -9.14},
+	"RO/bucharest":        {44.43, 26.10},
+	"RU/moscow":           {55.76, 37.62},
+	"SA/riyadh":           {24.71, 46.68},
+	"SE/stockholm":        {59.33, 18.07},
+	"SG/singapore":        {1.35, 103.82},
+	"TH/bangkok":          {13.76, 100.50},
+	"TR/istanbul":         {41.01, 28.98},
+	"TW/taipei":           {25.03, 121.57},
+	"UA/kyiv":             {50.45, 30.52},
+	"US/new york":         {40.71, -74.01},
+	"US/ashburn":          {39.04, -77.49},
+	"US/atlanta":          {33.75, -84.39},
+	"US/boston":           {42.36, -71.06},
+	"US/chicago":          {41.88, -87.63},
+	"US/dallas":           {32.78, -96.80},
+	"US/denver":           {39.74, -104.99},
+	"US/los angeles":      {34.05, -118.24},
+	"US/miami":            {25.76, -80.19},
+	"US/phoenix":          {33.45, -112.07},
+	"US/san jose":         {37.34, -121.89},
+	"US/san francisco":    {37.77, -122.42},
+	"US/seattle":          {47.61, -122.33},
+	"US/washington":       {38.91, -77.04},
+	"VN/hanoi":            {21.03, 105.85},
+	"VN/ho chi minh city": {10.82, 106.63},
+	"ZA/johannesburg":     {-26.20, 28.05},
+	"ZA/cape town":        {-33.92, 18.42},
+}
+
+// findCountry looks country up by ISO code or name, case insensitive
+func findCountry(codeOrName string) (country, bool) {
+	for _, c := range countries {
+		if strings.EqualFold(c.Code, codeOrName) || strings.EqualFold(c.Name, codeOrName) {
+			return c, true
+		}
+	}
+
+	return country{}, false
+}
+
+// findCityCoordinates looks coordinates of city in country up, case insensitive
+func findCityCoordinates(countryCode, city string) (coordinates, bool) {
+	c, ok := cityCoordinates[strings.ToUpper(countryCode)+"/"+strings.ToLower(city)]
+	return c, ok
+}
+
+// distanceKm is great-circle distance between two points on Earth
+func distanceKm(a, b coordinates) float64 {
+	const earthRadiusKm = 6371
+
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
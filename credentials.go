@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	common "github.com/apiheat/akamai-cli-common"
+	edgegrid "github.com/apiheat/go-edgegrid/v6/edgegrid"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	credentialHelperEnvVar  = "AKAMAI_CREDENTIAL_HELPER"
+	credentialSectionEnvVar = "AKAMAI_CREDENTIAL_SECTION"
+	credentialHelperTimeout = 30 * time.Second
+)
+
+// credentialHelperFlag runs external command which prints credentials
+func credentialHelperFlag() cli.Flag {
+	return cli.StringFlag{
+		Name:   "credential-helper",
+		Usage:  "Run `COMMAND` which prints credentials as JSON with host, client_token, client_secret and access_token fields instead of reading credentials file",
+		EnvVar: credentialHelperEnvVar,
+	}
+}
+
+// loadCredentials reads credentials selected by global flags and tells
+// where they were found. Sources are tried in order:
+//
+//  1. credential helper given with --credential-helper
+//  2. credentials file given with --config
+//  3. AKAMAI_HOST, AKAMAI_CLIENT_TOKEN, AKAMAI_CLIENT_SECRET and AKAMAI_ACCESS_TOKEN environment variables
+//  4. ~/.edgerc
+//
+// Section given with --section applies to credentials files and is passed
+// to credential helper. Environment variables are skipped when --section is
+// given or when only some of them are set.
+func loadCredentials(c *cli.Context) (*edgegrid.Credentials, string, error) {
+	file, section := c.GlobalString("config"), c.GlobalString("section")
+
+	if helper := c.GlobalString("credential-helper"); helper != "" {
+		creds, err := credentialsFromHelper(helper, section)
+		if err != nil {
+			return nil, "", authError{err: fmt.Errorf("Cannot load credentials from credential helper: %s", err)}
+		}
+
+		return creds, fmt.Sprintf("credential helper '%s'", helper), nil
+	}
+
+	if file == common.HomeDir() && !c.GlobalIsSet("section") {
+		creds, err := edgegrid.NewCredentials().FromEnv()
+		if err == nil {
+			return creds, "environment variables", nil
+		}
+
+		log.Debugf("Not using credentials from environment variables: %s", err)
+	}
+
+	creds, err := edgegrid.NewCredentials().FromFile(file).Section(section)
+	if err != nil {
+		return nil, "", authError{err: fmt.Errorf("Cannot load credentials from section '%s' of %s: %s. Run 'config verify' to check all sections", section, file, err)}
+	}
+
+	return creds, fmt.Sprintf("section '%s' of %s", section, file), nil
+}
+
+// credentialsFromHelper runs helper command with shell and reads JSON
+// credentials it prints to stdout. Selected section is passed to helper in
+// AKAMAI_CREDENTIAL_SECTION environment variable. Stdin is not passed to
+// helper, it may hold targets read with '--input -', helpers asking for
+// passphrase have to use terminal directly. Stderr is left to helper.
+func credentialsFromHelper(helper, section string) (*edgegrid.Credentials, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), credentialHelperTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", helper)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", helper)
+	}
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), credentialSectionEnvVar+"="+section)
+
+	log.Debugf("Running credential helper '%s' for section '%s'", helper, section)
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("helper did not finish in %s", credentialHelperTimeout)
+		}
+		return nil, err
+	}
+
+	if !json.Valid(stdout.Bytes()) {
+		return nil, fmt.Errorf("helper output is not valid JSON")
+	}
+
+	return edgegrid.NewCredentials().FromJSON(stdout.String())
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCredentialChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "edgerc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	edgerc := filepath.Join(dir, ".edgerc")
+	ioutil.WriteFile(edgerc, []byte(testEdgerc), 0600)
+
+	env := map[string]string{
+		"AKAMAI_HOST":          "akab-env.luna.akamaiapis.net",
+		"AKAMAI_CLIENT_TOKEN":  "akab-env-client-token",
+		"AKAMAI_CLIENT_SECRET": "env-secret",
+		"AKAMAI_ACCESS_TOKEN":  "akab-env-access-token",
+	}
+	for name, value := range env {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	helper := `echo '{"host": "akab-'$AKAMAI_CREDENTIAL_SECTION'.luna.akamaiapis.net", "client_token": "akab-helper-client", "client_secret": "helper-secret", "access_token": "akab-helper-access"}'`
+
+	runCommandTests(t, []commandTest{
+		{
+			name:     "environment variables",
+			args:     []string{"config", "show"},
+			contains: []string{`"source": "environment variables"`, `"host": "akab-env.luna.akamaiapis.net"`},
+		},
+		{
+			name:     "config file before environment variables",
+			args:     []string{"--config", edgerc, "config", "show"},
+			contains: []string{`"source": "section 'default' of ` + edgerc, `"host": "akab-default.luna.akamaiapis.net"`},
+		},
+		{
+			name:     "credential helper before everything else",
+			args:     []string{"--config", edgerc, "--section", "staging", "--credential-helper", helper, "config", "show"},
+			contains: []string{`"source": "credential helper`, `"host": "akab-staging.luna.akamaiapis.net"`, `"clientSecret": "****"`},
+		},
+		{
+			name:     "credential helper failure",
+			args:     []string{"--credential-helper", "exit 1", "config", "show"},
+			exitCode: exitAuth,
+		},
+		{
+			name:     "credential helper invalid output",
+			args:     []string{"--credential-helper", "echo not json", "config", "show"},
+			exitCode: exitAuth,
+		},
+		{
+			name:     "credential helper incomplete output",
+			args:     []string{"--credential-helper", `echo '{"host": "akab-helper.luna.akamaiapis.net"}'`, "config", "show"},
+			exitCode: exitAuth,
+		},
+	})
+
+	api := newFakeAPI()
+	defer api.Close()
+
+	// Default credentials file is read instead of environment variables
+	fromFile := func(args []string, section string) {
+		t.Helper()
+
+		out, err := runCommand(t, api, args...)
+		if strings.Contains(out, `"source": "environment variables"`) || (err != nil && !strings.Contains(err.Error(), "section '"+section+"'")) {
+			t.Errorf("%v: credentials not read from section '%s' of default file, output: %s, error: %v", args, section, out, err)
+		}
+	}
+
+	fromFile([]string{"--section", "staging", "config", "show"}, "staging")
+
+	os.Unsetenv("AKAMAI_ACCESS_TOKEN")
+
+	fromFile([]string{"config", "show"}, "default")
+}
+
+func TestCredentialsFromHelperMissingFields(t *testing.T) {
+	_, err := credentialsFromHelper(`echo '{"host": "akab-helper.luna.akamaiapis.net", "client_token": "akab-client"}'`, "default")
+	if err == nil || !strings.Contains(err.Error(), "ClientSecret") || !strings.Contains(err.Error(), "AccessToken") {
+		t.Errorf("credentialsFromHelper() error = %v", err)
+	}
+}
+
+func TestCredentialsFromHelperKeepsStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString("23.15.7.10\n")
+	w.Close()
+
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	// Helper reading stdin must not consume targets piped to the tool
+	helper := `cat > /dev/null; echo '{"host": "akab-helper.luna.akamaiapis.net", "client_token": "akab-client", "client_secret": "secret", "access_token": "akab-access"}'`
+	if _, err := credentialsFromHelper(helper, "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	targets, err := readBatchTargets("-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(targets) != 1 || targets[0].Target != "23.15.7.10" {
+		t.Errorf("targets read after helper = %v, want 23.15.7.10", targets)
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// akamaiPragma asks edge server to return Akamai debug headers
+const akamaiPragma = "Pragma: akamai-x-cache-on, akamai-x-cache-remote-on, akamai-x-check-cacheable, akamai-x-get-cache-key, akamai-x-get-true-cache-key, akamai-x-serial-no, akamai-x-get-request-id"
+
+// defaultUserAgent is sent as it is, the way it always was
+const defaultUserAgent = "Chrome"
+
+// curlMethods are HTTP methods curl can use, GET is default and is not sent to
+// API. HEAD is also sent for --headers-only.
+var curlMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+
+// userAgentPresets are well known user agents selectable by name with --user-agent
+var userAgentPresets = map[string]string{
+	"firefox":   "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:118.0) Gecko/20100101 Firefox/118.0",
+	"safari":    "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
+	"edge":      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36 Edg/118.0.2088.46",
+	"iphone":    "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+	"android":   "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Mobile Safari/537.36",
+	"googlebot": "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+	"bingbot":   "Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)",
+	"curl":      "curl/8.4.0",
+}
+
+// userAgentAliases point generic preset names to concrete presets
+var userAgentAliases = map[string]string{
+	"mobile": "iphone",
+	"bot":    "googlebot",
+}
+
+// Outcomes of request for cache
+const (
+	cacheHit     = "hit"
+	cacheStale   = "stale"
+	cacheMiss    = "miss"
+	cacheDenied  = "denied"
+	cacheError   = "error"
+	cacheUnknown = "unknown"
+)
+
+// cacheStatus tells outcome of cache status reported in X-Cache header and explains it
+type cacheStatus struct {
+	cache       string
+	explanation string
+}
+
+// cacheStatuses are known cache statuses of X-Cache header. Statuses missing
+// here are reported as unknown, or as error when they start with 'ERR_'.
+var cacheStatuses = map[string]cacheStatus{
+	"TCP_HIT":              {cacheHit, "Object was fresh in cache and served from it"},
+	"TCP_MEM_HIT":          {cacheHit, "Object was fresh in memory cache and served from it"},
+	"TCP_MISS":             {cacheMiss, "Object was not in cache, it was fetched from origin or parent"},
+	"TCP_REFRESH_HIT":      {cacheHit, "Object was stale in cache and successfully revalidated with origin"},
+	"TCP_REFRESH_MISS":     {cacheMiss, "Object was stale in cache and new object was fetched from origin"},
+	"TCP_REFRESH_FAIL_HIT": {cacheStale, "Object was stale in cache, revalidation failed and stale object was served"},
+	"TCP_IMS_HIT":          {cacheHit, "If-Modified-Since request was answered from fresh object in cache"},
+	"TCP_NEGATIVE_HIT":     {cacheHit, "Cached error response of origin was served"},
+	"TCP_COOKIE_DENY":      {cacheMiss, "Object was not cached because of cookie"},
+	"TCP_DENIED":           {cacheDenied, "Request was denied by edge server"},
+}
+
+// xCachePattern matches X-Cache header, e.g.
+// 'TCP_MISS from a23-15-7-10.deploy.akamaitechnologies.com (AkamaiGHost/10.0.0-1) (-)'
+var xCachePattern = regexp.MustCompile(`^(\S+)\s+from\s+(\S+)`)
+
+// serverTimingDuration matches duration of Server-Timing metric
+var serverTimingDuration = regexp.MustCompile(`dur=([\d.]+)`)
+
+// serverTimingDescription matches description of Server-Timing metric
+var serverTimingDescription = regexp.MustCompile(`desc=("?)([^;"]*)("?)`)
+
+// curlReport is curl result with all response headers, timing and decoded Akamai debug headers
+type curlReport struct {
+	URL             string            `json:"url"`
+	HTTPStatusCode  int               `json:"httpStatusCode"`
+	Timing          curlTiming        `json:"timing"`
+	Akamai          *akamaiDebug      `json:"akamai,omitempty"`
+	ResponseHeaders map[string]string `json:"responseHeaders"`
+	ResponseBody    string            `json:"responseBody,omitempty"`
+}
+
+// curlTiming is what can be measured of curl run by Diagnostic Tools,
+// durations are in milliseconds. APIDuration is round trip of the API call,
+// so it includes time the API needs to run curl, not only the request from
+// edge server. ServerTiming is breakdown reported by edge server and origin
+// in Server-Timing header, only present when property enables the header.
+type curlTiming struct {
+	APIDuration  float64        `json:"apiDuration"`
+	ServerTiming []serverTiming `json:"serverTiming,omitempty"`
+}
+
+type serverTiming struct {
+	Name        string  `json:"name"`
+	Duration    float64 `json:"duration,omitempty"`
+	Description string  `json:"description,omitempty"`
+}
+
+// akamaiDebug holds values decoded from Akamai debug headers
+type akamaiDebug struct {
+	Cache                string `json:"cache,omitempty"`
+	CacheStatus          string `json:"cacheStatus,omitempty"`
+	CacheExplanation     string `json:"cacheExplanation,omitempty"`
+	EdgeServer           string `json:"edgeServer,omitempty"`
+	RemoteCacheStatus    string `json:"remoteCacheStatus,omitempty"`
+	RemoteServer         string `json:"remoteServer,omitempty"`
+	Cacheable            string `json:"cacheable,omitempty"`
+	CacheableExplanation string `json:"cacheableExplanation,omitempty"`
+	CacheKey             string `json:"cacheKey,omitempty"`
+	CPCode               string `json:"cpCode,omitempty"`
+	TTL                  string `json:"ttl,omitempty"`
+	Origin               string `json:"origin,omitempty"`
+	TrueCacheKey         string `json:"trueCacheKey,omitempty"`
+	Serial               string `json:"serial,omitempty"`
+	RequestID            string `json:"requestId,omitempty"`
+}
+
+// curlFlags returns flags shared by ip and ghost curl
+func curlFlags() []cli.Flag {
+	return append(append([]cli.Flag{
+		cli.StringFlag{
+			Name:  "url",
+			Value: "",
+			Usage: "The URL for which to gather a curl response",
+		},
+	}, curlRequestFlags()...),
+		cli.BoolFlag{
+			Name:  "headers-only",
+			Usage: "Request only status code and response headers, sent as HEAD request, cannot be used with --method",
+		},
+		cli.BoolFlag{
+			Name:  "pragma",
+			Usage: "Send Akamai debug Pragma headers and decode X-Cache, X-Check-Cacheable, X-Cache-Key, X-True-Cache-Key and X-Serial from the response",
+		},
+	)
+}
+
+// curlRequestFlags returns flags shaping curl request
+func curlRequestFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "user-agent",
+			Value: defaultUserAgent,
+			Usage: fmt.Sprintf("A header field to spoof a type of browser, either literal value or one of presets: %s", strings.Join(userAgentPresetNames(), ", ")),
+		},
+		cli.StringFlag{
+			Name:  "method",
+			Value: "",
+			Usage: fmt.Sprintf("HTTP `METHOD` of request, one of: %s ( default: GET )", strings.Join(curlMethods, ", ")),
+		},
+		cli.StringSliceFlag{
+			Name:  "header, H",
+			Usage: "Add request `HEADER`, e.g. 'Host: www.example.com' or 'Accept-Encoding: gzip', can be repeated",
+		},
+	}
+}
+
+// curlRequestFromFlags builds curl request from command flags
+func curlRequestFromFlags(f flagSource) curlRequest {
+	request := curlRequest{URL: f.String("url"), UserAgent: resolveUserAgent(f.String("user-agent"))}
+
+	for _, header := range f.StringSlice("header") {
+		request.RequestHeaders = append(request.RequestHeaders, strings.TrimSpace(header))
+	}
+
+	if f.Bool("pragma") {
+		request.RequestHeaders = append(request.RequestHeaders, akamaiPragma)
+	}
+
+	if method := strings.ToUpper(f.String("method")); method != "" && method != http.MethodGet {
+		request.Method = method
+	}
+
+	if f.Bool("headers-only") {
+		request.Method = http.MethodHead
+	}
+
+	return request
+}
+
+// runCurl executes curl from ghost location or IP address and analyses its result
+func runCurl(obj, requestFrom string, f flagSource) (curlReport, error) {
+	request := curlRequestFromFlags(f)
+
+	started := time.Now()
+	response, err := apiClient.ExecuteCurlRequest(obj, requestFrom, request)
+	if err != nil {
+		return curlReport{}, err
+	}
+
+	report := newCurlReport(request.URL, response, time.Since(started))
+	if f.Bool("headers-only") {
+		report.ResponseBody = ""
+	}
+
+	return report, nil
+}
+
+// resolveUserAgent expands preset name into user agent, other values are used as they are
+func resolveUserAgent(value string) string {
+	name := strings.ToLower(strings.TrimSpace(value))
+	if alias, ok := userAgentAliases[name]; ok {
+		name = alias
+	}
+
+	if ua, ok := userAgentPresets[name]; ok {
+		return ua
+	}
+
+	return value
+}
+
+func userAgentPresetNames() []string {
+	var names []string
+	for name := range userAgentPresets {
+		names = append(names, name)
+	}
+	for name := range userAgentAliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func newCurlReport(url string, response *curlResult, elapsed time.Duration) curlReport {
+	results := response.CurlResults
+
+	report := curlReport{
+		URL:             url,
+		HTTPStatusCode:  results.HTTPStatusCode,
+		ResponseHeaders: map[string]string{},
+		ResponseBody:    results.ResponseBody,
+	}
+
+	for name, value := range results.ResponseHeaders {
+		report.ResponseHeaders[http.CanonicalHeaderKey(name)] = value
+	}
+
+	report.Timing.APIDuration = float64(elapsed) / float64(time.Millisecond)
+	report.Timing.ServerTiming = parseServerTiming(report.ResponseHeaders["Server-Timing"])
+	report.Akamai = decodeAkamaiHeaders(report.ResponseHeaders)
+
+	return report
+}
+
+// parseServerTiming reads metrics of Server-Timing header, e.g.
+// 'cdn-cache; desc=MISS, edge; dur=12, origin; dur=35'
+func parseServerTiming(header string) []serverTiming {
+	var timings []serverTiming
+
+	for _, metric := range strings.Split(header, ",") {
+		params := strings.Split(metric, ";")
+
+		name := strings.TrimSpace(params[0])
+		if name == "" {
+			continue
+		}
+
+		timing := serverTiming{Name: name}
+		if m := serverTimingDuration.FindStringSubmatch(metric); m != nil {
+			timing.Duration, _ = strconv.ParseFloat(m[1], 64)
+		}
+		if m := serverTimingDescription.FindStringSubmatch(metric); m != nil {
+			timing.Description = strings.TrimSpace(m[2])
+		}
+
+		timings = append(timings, timing)
+	}
+
+	return timings
+}
+
+// decodeAkamaiHeaders explains Akamai debug headers, it returns nil when there are none
+func decodeAkamaiHeaders(headers map[string]string) *akamaiDebug {
+	var debug akamaiDebug
+	found := false
+
+	if v, ok := headers["X-Cache"]; ok {
+		found = true
+		debug.CacheStatus, debug.EdgeServer = parseXCache(v)
+
+		status, ok := cacheStatuses[debug.CacheStatus]
+		switch {
+		case ok:
+			debug.Cache, debug.CacheExplanation = status.cache, status.explanation
+		case strings.HasPrefix(debug.CacheStatus, "ERR_"):
+			debug.Cache, debug.CacheExplanation = cacheError, "Edge server failed to serve request"
+		default:
+			debug.Cache = cacheUnknown
+		}
+	}
+
+	if v, ok := headers["X-Cache-Remote"]; ok {
+		found = true
+		debug.RemoteCacheStatus, debug.RemoteServer = parseXCache(v)
+	}
+
+	if v, ok := headers["X-Check-Cacheable"]; ok {
+		found = true
+		debug.Cacheable = strings.ToUpper(strings.TrimSpace(v))
+
+		switch debug.Cacheable {
+		case "YES":
+			debug.CacheableExplanation = "Object may be cached by edge server according to configuration"
+		case "NO":
+			debug.CacheableExplanation = "Object is not cached, configuration or response headers prevent caching"
+		}
+	}
+
+	if v, ok := headers["X-Cache-Key"]; ok {
+		found = true
+		debug.CacheKey = v
+		debug.CPCode, debug.TTL, debug.Origin = parseCacheKey(v)
+	}
+
+	if v, ok := headers["X-True-Cache-Key"]; ok {
+		found = true
+		debug.TrueCacheKey = v
+	}
+
+	if v, ok := headers["X-Serial"]; ok {
+		found = true
+		debug.Serial = v
+	}
+
+	if v, ok := headers["X-Akamai-Request-Id"]; ok {
+		found = true
+		debug.RequestID = v
+	}
+
+	if !found {
+		return nil
+	}
+
+	return &debug
+}
+
+func parseXCache(value string) (status, server string) {
+	if m := xCachePattern.FindStringSubmatch(strings.TrimSpace(value)); m != nil {
+		return m[1], m[2]
+	}
+
+	return strings.TrimSpace(value), ""
+}
+
+// parseCacheKey extracts CP code, TTL and origin from X-Cache-Key, e.g.
+// 'S/L/1234/567890/1d/origin.example.com/index.html'. Key starts with one
+// letter markers followed by serial number, CP code, TTL and origin.
+func parseCacheKey(key string) (cpCode, ttl, origin string) {
+	parts := strings.Split(strings.TrimPrefix(key, "/"), "/")
+
+	i := 0
+	for i < len(parts) && len(parts[i]) <= 1 {
+		i++
+	}
+
+	// parts[i] is serial number
+	fields := parts[i:]
+	if len(fields) < 4 {
+		return "", "", ""
+	}
+
+	return fields[1], fields[2], fields[3]
+}
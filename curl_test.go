@@ -0,0 +1,154 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeAkamaiHeaders(t *testing.T) {
+	got := decodeAkamaiHeaders(map[string]string{
+		"X-Cache":           "TCP_REFRESH_HIT from a23-15-7-10.deploy.akamaitechnologies.com (AkamaiGHost/10.0.0-1) (-)",
+		"X-Cache-Remote":    "TCP_MISS from a88-221-81-192.deploy.akamaitechnologies.com (AkamaiGHost/10.0.0-1) (-)",
+		"X-Check-Cacheable": "no",
+		"X-Cache-Key":       "/L/1234/567890/30m/origin.example.com/images/logo.png?v=1",
+		"X-Serial":          "1234",
+	})
+
+	want := &akamaiDebug{
+		Cache:                "hit",
+		CacheStatus:          "TCP_REFRESH_HIT",
+		CacheExplanation:     cacheStatuses["TCP_REFRESH_HIT"].explanation,
+		EdgeServer:           "a23-15-7-10.deploy.akamaitechnologies.com",
+		RemoteCacheStatus:    "TCP_MISS",
+		RemoteServer:         "a88-221-81-192.deploy.akamaitechnologies.com",
+		Cacheable:            "NO",
+		CacheableExplanation: "Object is not cached, configuration or response headers prevent caching",
+		CacheKey:             "/L/1234/567890/30m/origin.example.com/images/logo.png?v=1",
+		CPCode:               "567890",
+		TTL:                  "30m",
+		Origin:               "origin.example.com",
+		Serial:               "1234",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeAkamaiHeaders() = %+v, want %+v", got, want)
+	}
+
+	if got := decodeAkamaiHeaders(map[string]string{"Server": "AkamaiGHost"}); got != nil {
+		t.Errorf("decodeAkamaiHeaders() without debug headers = %+v, want nil", got)
+	}
+}
+
+func TestDecodeCacheOutcome(t *testing.T) {
+	tests := []struct {
+		xCache string
+		want   string
+	}{
+		{"TCP_MEM_HIT from a23-15-7-10.deploy.akamaitechnologies.com (AkamaiGHost/10.0.0-1) (-)", cacheHit},
+		{"TCP_REFRESH_MISS from a23-15-7-10.deploy.akamaitechnologies.com (AkamaiGHost/10.0.0-1) (-)", cacheMiss},
+		{"TCP_REFRESH_FAIL_HIT from a23-15-7-10.deploy.akamaitechnologies.com (AkamaiGHost/10.0.0-1) (-)", cacheStale},
+		{"TCP_DENIED from a23-15-7-10.deploy.akamaitechnologies.com (AkamaiGHost/10.0.0-1) (-)", cacheDenied},
+		{"ERR_CONNECT_FAIL from a23-15-7-10.deploy.akamaitechnologies.com (AkamaiGHost/10.0.0-1) (-)", cacheError},
+		{"TCP_SOMETHING_NEW", cacheUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := decodeAkamaiHeaders(map[string]string{"X-Cache": tt.xCache}); got.Cache != tt.want {
+			t.Errorf("decodeAkamaiHeaders(%q).Cache = %q, want %q", tt.xCache, got.Cache, tt.want)
+		}
+	}
+}
+
+func TestParseServerTiming(t *testing.T) {
+	got := parseServerTiming(`cdn-cache; desc=HIT, edge; dur=1, origin; dur=0.5, misc; desc="a b"`)
+	want := []serverTiming{
+		{Name: "cdn-cache", Description: "HIT"},
+		{Name: "edge", Duration: 1},
+		{Name: "origin", Duration: 0.5},
+		{Name: "misc", Description: "a b"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseServerTiming() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCurlOutput(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name:     "headers and timing",
+			args:     []string{"ip", "curl", "--url", "https://www.example.com/", "23.15.7.10"},
+			contains: []string{`"Server-Timing": "cdn-cache; desc=MISS, edge; dur=12, origin; dur=35"`, `"name": "origin",`, `"duration": 35`},
+		},
+		{
+			name:     "pragma",
+			args:     []string{"ghost", "curl", "--url", "https://www.example.com/", "--pragma", "paris-france"},
+			contains: []string{`"cache": "miss"`, `"cacheStatus": "TCP_MISS"`, `"cacheable": "YES"`, `"cpCode": "567890"`, `"ttl": "1d"`, `"origin": "origin.example.com"`, `"serial": "1234"`},
+		},
+	})
+}
+
+func TestCurlRequestOptions(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name:     "default user agent and method",
+			args:     []string{"ip", "curl", "--url", "https://www.example.com/", "23.15.7.10"},
+			contains: []string{`"X-Fake-User-Agent": "Chrome"`, `"X-Fake-Method": ""`, `"responseBody": "<html></html>"`},
+		},
+		{
+			name:     "preset alias",
+			args:     []string{"ghost", "curl", "--url", "https://www.example.com/", "--user-agent", "mobile", "paris-france"},
+			contains: []string{`"X-Fake-User-Agent": "` + userAgentPresets["iphone"] + `"`},
+		},
+		{
+			name:     "literal user agent",
+			args:     []string{"ip", "curl", "--url", "https://www.example.com/", "--user-agent", "MyMonitor/1.0", "23.15.7.10"},
+			contains: []string{`"X-Fake-User-Agent": "MyMonitor/1.0"`},
+		},
+		{
+			name:     "repeated headers",
+			args:     []string{"ghost", "curl", "--url", "https://www.example.com/", "--header", "Host: www.example.org", "--header", "Cookie: a=b", "--pragma", "paris-france"},
+			contains: []string{`"X-Fake-Request-Headers": "Host: www.example.org | Cookie: a=b | Pragma: akamai-x-cache-on`},
+		},
+		{
+			name:     "invalid header",
+			args:     []string{"ip", "curl", "--url", "https://www.example.com/", "--header", "no colon", "23.15.7.10"},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "head method",
+			args:     []string{"ghost", "curl", "--url", "https://www.example.com/", "--method", "head", "paris-france"},
+			contains: []string{`"X-Fake-Method": "HEAD"`},
+		},
+		{
+			name:     "unsupported method",
+			args:     []string{"ip", "curl", "--url", "https://www.example.com/", "--method", "POST", "23.15.7.10"},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "headers only",
+			args:     []string{"ip", "curl", "--url", "https://www.example.com/", "--headers-only", "23.15.7.10"},
+			contains: []string{`"httpStatusCode": 200`, `"Content-Type": "text/html"`, `"X-Fake-Method": "HEAD"`},
+		},
+		{
+			name:     "headers only with method",
+			args:     []string{"ip", "curl", "--url", "https://www.example.com/", "--headers-only", "--method", "GET", "23.15.7.10"},
+			exitCode: exitValidation,
+		},
+	})
+}
+
+func TestCurlHeadersOnlyDropsBody(t *testing.T) {
+	api := newFakeAPI()
+	defer api.Close()
+
+	out, err := runCommand(t, api, "ip", "curl", "--url", "https://www.example.com/", "--headers-only", "23.15.7.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(out, "responseBody") {
+		t.Errorf("output contains response body:\n%s", out)
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	common "github.com/apiheat/akamai-cli-common"
+	service "github.com/apiheat/go-edgegrid/v6/service/diagnosticv2"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+func cmdDiagnose(c *cli.Context) error {
+	return diagnose(c)
+}
+
+// Statuses of diagnose checks
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// diagnoseQueryTypes are looked up for hostname, so both IPv4 and IPv6 edge
+// addresses are checked
+var diagnoseQueryTypes = []string{"A", "AAAA"}
+
+// diagnosisCheck is outcome of single diagnose step
+type diagnosisCheck struct {
+	Check   string `json:"check"`
+	Status  string `json:"status"`
+	Details string `json:"details"`
+}
+
+// diagnosis is the outcome of all diagnose steps for hostname together with
+// results of diagnostic tools they are based on
+type diagnosis struct {
+	Hostname  string           `json:"hostname"`
+	URL       string           `json:"url"`
+	Origin    string           `json:"origin,omitempty"`
+	Locations []string         `json:"locations"`
+	Checks    []diagnosisCheck `json:"checks"`
+	Dig       locationReport   `json:"dig"`
+	EdgeIPs   []batchRecord    `json:"edgeIps,omitempty"`
+	Curl      []batchRecord    `json:"curl,omitempty"`
+	Mtr       locationReport   `json:"mtr,omitempty"`
+}
+
+func (d diagnosis) tableHeader() []string {
+	return []string{"check", "status", "details"}
+}
+
+func (d diagnosis) tableRows() [][]string {
+	var rows [][]string
+	for _, check := range d.Checks {
+		rows = append(rows, []string{check.Check, check.Status, check.Details})
+	}
+
+	return rows
+}
+
+func (d *diagnosis) addCheck(check, status, format string, args ...interface{}) {
+	details := fmt.Sprintf(format, args...)
+
+	switch status {
+	case checkPass:
+		log.Infof("%s: %s", check, details)
+	default:
+		log.Warnf("%s: %s", check, details)
+	}
+
+	d.Checks = append(d.Checks, diagnosisCheck{Check: check, Status: status, Details: details})
+}
+
+// checkError returns error naming failed checks, nil when none failed
+func (d diagnosis) checkError() error {
+	var failed []string
+	for _, check := range d.Checks {
+		if check.Status == checkFail {
+			failed = append(failed, check.Check)
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return checkFailedError{msg: fmt.Sprintf("Diagnosis of %s failed checks: %s", d.Hostname, strings.Join(failed, ", "))}
+}
+
+// diagnose resolves hostname from ghost locations, checks resolved addresses
+// are Akamai edge servers, curls URL from them and runs mtr to origin from
+// the same ghost locations. It fails when any of checks fails.
+func diagnose(c *cli.Context) error {
+	hostname, err := requireArgument(c, "Please provide HOSTNAME")
+	if err != nil {
+		return err
+	}
+
+	testURL := c.String("url")
+	if testURL == "" {
+		testURL = fmt.Sprintf("https://%s/", hostname)
+	}
+
+	// Pragma headers are always sent as origin is read from cache key
+	f := rowFlags{c: c, row: map[string]string{"hostname": hostname, "query-type": "A", "url": testURL, "pragma": "true", "destination-domain": c.String("origin")}}
+	if err := validateDigOptions(f); err != nil {
+		return err
+	}
+
+	if err := validateCurlOptions(f); err != nil {
+		return err
+	}
+
+	if c.String("origin") != "" {
+		if err := validateMtrOptions(f); err != nil {
+			return err
+		}
+	}
+
+	locations, err := selectGhostLocations(c)
+	if err != nil {
+		return err
+	}
+
+	result := diagnosis{Hostname: hostname, URL: testURL, Origin: c.String("origin"), Locations: locations}
+
+	addresses := diagnoseDNS(c, &result, f)
+	edgeIPs := diagnoseEdgeIPs(c, &result, addresses)
+	diagnoseCurl(c, &result, edgeIPs, f)
+	diagnoseOriginMtr(c, &result)
+
+	if err := printOutput(c, result); err != nil {
+		return err
+	}
+
+	return result.checkError()
+}
+
+// diagnoseDNS digs A and AAAA records of hostname from every location and
+// returns unique addresses it resolves to. Result of every location holds
+// dig report per query type.
+func diagnoseDNS(c *cli.Context, result *diagnosis, f flagSource) []string {
+	result.Dig = fanOut(result.Locations, c.Int("workers"), func(location string) (interface{}, error) {
+		digs := map[string]digReport{}
+		for _, queryType := range diagnoseQueryTypes {
+			response, err := apiClient.ExecuteDig(location, requestFromGhost, f.String("hostname"), queryType)
+			if err != nil {
+				return nil, err
+			}
+
+			digs[queryType] = newDigReport(response)
+		}
+
+		return digs, nil
+	})
+
+	var addresses, unresolved []string
+	for _, location := range result.Dig.locations() {
+		res := result.Dig[location]
+		if res.Error != "" {
+			unresolved = append(unresolved, location)
+			continue
+		}
+
+		var found []string
+		for _, dig := range res.Result.(map[string]digReport) {
+			found = append(found, dig.addresses()...)
+		}
+
+		if len(found) == 0 {
+			unresolved = append(unresolved, location)
+		}
+		addresses = append(addresses, found...)
+	}
+
+	addresses = common.RemoveStringDuplicates(addresses)
+	sort.Strings(addresses)
+
+	switch {
+	case len(unresolved) == len(result.Locations):
+		result.addCheck("dns", checkFail, "%s did not resolve from any of %d locations", result.Hostname, len(result.Locations))
+	case len(unresolved) > 0:
+		result.addCheck("dns", checkWarn, "resolved to %s, but not from %s", strings.Join(addresses, ", "), strings.Join(unresolved, ", "))
+	default:
+		result.addCheck("dns", checkPass, "resolved to %s from %d locations", strings.Join(addresses, ", "), len(result.Locations))
+	}
+
+	return addresses
+}
+
+// diagnoseEdgeIPs checks which addresses belong to Akamai edge servers and returns them
+func diagnoseEdgeIPs(c *cli.Context, result *diagnosis, addresses []string) []string {
+	if len(addresses) == 0 {
+		result.addCheck("edge-ips", checkFail, "skipped, no addresses to check")
+		return nil
+	}
+
+	result.EdgeIPs = runBatch(c, targetsOf(addresses), func(ip string, f flagSource) (interface{}, error) {
+		response, err := apiClient.CheckIPAddress(ip)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	})
+
+	var edgeIPs, others, failed []string
+	for _, record := range result.EdgeIPs {
+		switch {
+		case record.Error != "":
+			failed = append(failed, record.Target)
+		case record.Result.(*service.CDNStatus).IsAkamai:
+			edgeIPs = append(edgeIPs, record.Target)
+		default:
+			others = append(others, record.Target)
+		}
+	}
+
+	switch {
+	case len(edgeIPs) == 0 && len(failed) == len(addresses):
+		result.addCheck("edge-ips", checkFail, "could not check %s", strings.Join(failed, ", "))
+	case len(edgeIPs) == 0:
+		result.addCheck("edge-ips", checkFail, "%s are not Akamai edge IPs, hostname is not served by Akamai", strings.Join(others, ", "))
+	case len(others) > 0 || len(failed) > 0:
+		result.addCheck("edge-ips", checkWarn, "%s are Akamai edge IPs, but %s are not or could not be checked", strings.Join(edgeIPs, ", "), strings.Join(append(others, failed...), ", "))
+	default:
+		result.addCheck("edge-ips", checkPass, "all %d addresses are Akamai edge IPs", len(edgeIPs))
+	}
+
+	return edgeIPs
+}
+
+// diagnoseCurl requests URL from every edge IP and reads origin from cache
+// key when --origin is not given
+func diagnoseCurl(c *cli.Context, result *diagnosis, edgeIPs []string, f flagSource) {
+	if len(edgeIPs) == 0 {
+		result.addCheck("curl", checkFail, "skipped, no Akamai edge IPs to request %s from", result.URL)
+		return
+	}
+
+	result.Curl = runBatch(c, targetsOf(edgeIPs), func(ip string, _ flagSource) (interface{}, error) {
+		return runCurl(ip, requestFromIP, f)
+	})
+
+	var ok, failed []string
+	for _, record := range result.Curl {
+		if record.Error != "" {
+			failed = append(failed, fmt.Sprintf("%s ( %s )", record.Target, record.Error))
+			continue
+		}
+
+		curl := record.Result.(curlReport)
+		if curl.HTTPStatusCode >= 400 {
+			failed = append(failed, fmt.Sprintf("%s ( HTTP %d )", record.Target, curl.HTTPStatusCode))
+			continue
+		}
+
+		ok = append(ok, fmt.Sprintf("%s ( HTTP %d )", record.Target, curl.HTTPStatusCode))
+
+		if result.Origin == "" && curl.Akamai != nil && curl.Akamai.Origin != "" {
+			result.Origin = curl.Akamai.Origin
+			log.Debugf("Origin %s found in cache key returned by %s", result.Origin, record.Target)
+		}
+	}
+
+	switch {
+	case len(ok) == 0:
+		result.addCheck("curl", checkFail, "%s failed from every edge IP: %s", result.URL, strings.Join(failed, ", "))
+	case len(failed) > 0:
+		result.addCheck("curl", checkWarn, "%s succeeded from %s, but failed from %s", result.URL, strings.Join(ok, ", "), strings.Join(failed, ", "))
+	default:
+		result.addCheck("curl", checkPass, "%s succeeded from %s", result.URL, strings.Join(ok, ", "))
+	}
+}
+
+// diagnoseOriginMtr runs mtr from every location to origin
+func diagnoseOriginMtr(c *cli.Context, result *diagnosis) {
+	if result.Origin == "" {
+		result.addCheck("origin-mtr", checkWarn, "skipped, origin is not known, please provide --origin")
+		return
+	}
+
+	result.Mtr = fanOut(result.Locations, c.Int("workers"), func(location string) (interface{}, error) {
+		response, err := apiClient.ExecuteMtr(location, requestFromGhost, result.Origin, false)
+		if err != nil {
+			return nil, err
+		}
+
+		return newMtrReport(response), nil
+	})
+
+	var reached, lossy, unreachable []string
+	for _, location := range result.Mtr.locations() {
+		res := result.Mtr[location]
+		if res.Error != "" {
+			unreachable = append(unreachable, location)
+			continue
+		}
+
+		mtr := res.Result.(mtrReport)
+		switch {
+		case !mtr.Findings.DestinationReached:
+			unreachable = append(unreachable, location)
+		case mtr.Findings.FirstLossHop > 0:
+			lossy = append(lossy, fmt.Sprintf("%s ( %s%% loss at hop %d )", location, formatFloat(mtr.Findings.FirstLoss), mtr.Findings.FirstLossHop))
+		default:
+			reached = append(reached, location)
+		}
+	}
+
+	switch {
+	case len(reached) == 0 && len(lossy) == 0:
+		result.addCheck("origin-mtr", checkFail, "origin %s is not reachable from any of %d locations", result.Origin, len(result.Locations))
+	case len(unreachable) > 0 || len(lossy) > 0:
+		result.addCheck("origin-mtr", checkWarn, "origin %s is reachable from %d of %d locations, with loss from %s, not reachable from %s",
+			result.Origin, len(reached)+len(lossy), len(result.Locations), joinOrNone(lossy), joinOrNone(unreachable))
+	default:
+		result.addCheck("origin-mtr", checkPass, "origin %s is reachable without loss from %d locations", result.Origin, len(reached))
+	}
+}
+
+// targetsOf turns values into batch targets without flag overrides
+func targetsOf(values []string) []batchTarget {
+	var targets []batchTarget
+	for _, v := range values {
+		targets = append(targets, batchTarget{Target: v})
+	}
+
+	return targets
+}
+
+func joinOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+
+	return strings.Join(values, ", ")
+}
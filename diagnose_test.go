@@ -0,0 +1,95 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLocationPerContinent(t *testing.T) {
+	tests := []struct {
+		known []ghostLocation
+		want  []string
+	}{
+		{
+			[]ghostLocation{
+				newGhostLocation("tokyo-japan", "Tokyo, Japan"),
+				newGhostLocation("paris-france", "Paris, France"),
+				newGhostLocation("berlin-germany", "Berlin, Germany"),
+			},
+			[]string{"tokyo-japan", "berlin-germany"},
+		},
+		{
+			[]ghostLocation{{ID: "b-nowhere"}, {ID: "a-nowhere"}},
+			[]string{"a-nowhere"},
+		},
+		{nil, nil},
+	}
+
+	for _, tt := range tests {
+		if got := locationPerContinent(tt.known); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("locationPerContinent(%v) = %v, want %v", tt.known, got, tt.want)
+		}
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name: "healthy hostname",
+			args: []string{"diagnose", "www.example.com"},
+			contains: []string{
+				`"origin": "origin.example.com"`,
+				`"berlin-germany"`,
+				`"tokyo-japan"`,
+				`"details": "resolved to 23.15.7.10, 2a02:26f0:d8::17d4:9d1 from 2 locations"`,
+				`"details": "all 2 addresses are Akamai edge IPs"`,
+				`"details": "https://www.example.com/ succeeded from 23.15.7.10 ( HTTP 200 ), 2a02:26f0:d8::17d4:9d1 ( HTTP 200 )"`,
+				`"details": "origin origin.example.com is reachable without loss from 2 locations"`,
+			},
+		},
+		{
+			name:     "table",
+			args:     []string{"--output", "table", "diagnose", "--locations", "paris-france", "www.example.com"},
+			contains: []string{"CHECK", "STATUS", "dns", "edge-ips", "curl", "origin-mtr", "pass"},
+		},
+		{
+			name: "lossy path to origin",
+			args: []string{"diagnose", "--locations", "paris-france", "--origin", fakeLossy + ".example.com", "www.example.com"},
+			contains: []string{
+				`"status": "warn"`,
+				`"details": "origin lossy.example.com is reachable from 1 of 1 locations, with loss from paris-france ( 30.0% loss at hop 2 ), not reachable from none"`,
+			},
+		},
+		{
+			name:     "unreachable origin",
+			args:     []string{"diagnose", "--locations", "*germany*", "--origin", fakeBroken + ".example.com", "www.example.com"},
+			exitCode: exitCheckFailed,
+			contains: []string{
+				`"origin": "broken.example.com"`,
+				`"status": "fail"`,
+				`"details": "origin broken.example.com is not reachable from any of 2 locations"`,
+			},
+		},
+		{
+			name:     "unresolved hostname",
+			args:     []string{"diagnose", "--locations", "paris-france", fakeBroken + ".example.com"},
+			exitCode: exitCheckFailed,
+			contains: []string{
+				`"details": "broken.example.com did not resolve from any of 1 locations"`,
+				`"details": "skipped, no addresses to check"`,
+				`"details": "skipped, no Akamai edge IPs to request https://broken.example.com/ from"`,
+				`"details": "skipped, origin is not known, please provide --origin"`,
+			},
+		},
+		{
+			name:     "without hostname",
+			args:     []string{"diagnose"},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "unknown location",
+			args:     []string{"diagnose", "--locations", "frankfurt-germny", "www.example.com"},
+			exitCode: exitValidation,
+		},
+	})
+}
@@ -1,15 +1,9 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/url"
-	"os"
-	"strconv"
-	"strings"
 
-	common "github.com/apiheat/akamai-cli-common"
-	log "github.com/sirupsen/logrus"
+	service "github.com/apiheat/go-edgegrid/v6/service/diagnosticv2"
 	"github.com/urfave/cli"
 )
 
@@ -26,56 +20,66 @@ func cmdGetLinkDetails(c *cli.Context) error {
 }
 
 func generateLink(c *cli.Context) error {
-	testURL := common.SetStringId(c, "Please provide URL you want to simulate the user loading")
-
-	_, err := url.Parse(testURL)
+	testURL, err := requireArgument(c, "Please provide URL you want to simulate the user loading")
 	if err != nil {
-		log.Error(fmt.Sprintf("URL you want to simulate the user loading is not valid URL '%s'", testURL))
-		os.Exit(3)
+		return err
 	}
 
-	response, err := apiClient.GenerateDiagnosticLink(c.String("user"), testURL)
-	common.ErrorCheck(err)
+	if _, err := url.Parse(testURL); err != nil {
+		return newValidationError("URL you want to simulate the user loading is not valid URL '%s'", testURL)
+	}
 
-	common.PrintJSON(outputJSON(response))
+	response, err := apiClient.GenerateDiagnosticLink(c.String("user"), testURL)
+	if err != nil {
+		return newAPIError(err)
+	}
 
-	return nil
+	return printOutput(c, response)
 }
 
 func listLinkRequests(c *cli.Context) error {
-	response, err := apiClient.ListDiagnosticLinkRequests()
-	common.ErrorCheck(err)
-
-	common.OutputJSON(response.EndUserIPRequests)
-	return nil
+	return runOnAccounts(c, func(client diagnosticClient) (interface{}, error) {
+		response, err := fetchDiagnosticLinkRequests(client)
+		if err != nil {
+			return nil, err
+		}
+
+		return response.EndUserIPRequests, nil
+	})
 }
 
-func getLinkRequest(c *cli.Context) error {
-	requestID := common.SetStringId(c, "Please provide valid Request ID")
+// fetchDiagnosticLinkRequests lists diagnostic link requests. Their IDs are
+// remembered for shell completion, unless they come from one of many accounts.
+func fetchDiagnosticLinkRequests(client diagnosticClient) (*service.DiagnosticLinkRequests, error) {
+	response, err := client.ListDiagnosticLinkRequests()
+	if err != nil {
+		return nil, newAPIError(err)
+	}
 
-	response, err := apiClient.RetrieveDiagnosticLinkRequest(requestID)
-	common.ErrorCheck(err)
+	if len(accountClients) > 0 {
+		return response, nil
+	}
+
+	ids := []string{}
+	for _, r := range response.EndUserIPRequests {
+		ids = append(ids, diagnosticLinkID(r.RequestID))
+	}
 
-	common.OutputJSON(response.EndUserIPDetails)
+	rememberCompletions(map[string][]string{completeDiagnosticLinks: ids})
 
-	return nil
+	return response, nil
 }
 
-func unescapeUnicodeCharactersInJSON(_jsonRaw json.RawMessage) (json.RawMessage, error) {
-	str, err := strconv.Unquote(strings.Replace(strconv.Quote(string(_jsonRaw)), `\\u`, `\u`, -1))
+func getLinkRequest(c *cli.Context) error {
+	requestID, err := requireArgument(c, "Please provide valid Request ID")
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return []byte(str), nil
-}
 
-func outputJSON(input interface{}) string {
-	b, err := json.Marshal(input)
+	response, err := apiClient.RetrieveDiagnosticLinkRequest(requestID)
 	if err != nil {
-		fmt.Println(err)
+		return newAPIError(err)
 	}
 
-	jsonRawUnescaped, _ := unescapeUnicodeCharactersInJSON(json.RawMessage(b))
-
-	return string(jsonRawUnescaped)
+	return printOutput(c, response.EndUserIPDetails)
 }
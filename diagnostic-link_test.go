@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestDiagnosticLinkCommands(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name:     "generate",
+			args:     []string{"diagnostic-link", "generate", "https://www.example.com/"},
+			contains: []string{`"diagnosticUrl": "https://fake.akamai.com/diagnostic/abc"`},
+		},
+		{
+			name:     "generate without url",
+			args:     []string{"diagnostic-link", "generate"},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "list",
+			args:     []string{"diagnostic-link", "list"},
+			contains: []string{`"requestId": 1234`},
+		},
+		{
+			name:     "get",
+			args:     []string{"diagnostic-link", "get", "1234"},
+			contains: []string{`"ip": "198.51.100.7"`},
+		},
+		{
+			name:     "get without request id",
+			args:     []string{"diagnostic-link", "get"},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "get unknown request id",
+			args:     []string{"diagnostic-link", "get", fakeMissing},
+			exitCode: exitNotFound,
+		},
+	})
+}
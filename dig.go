@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+
+	service "github.com/apiheat/go-edgegrid/v6/service/diagnosticv2"
+	"github.com/urfave/cli"
+)
+
+// dnsRecord is single resource record of dig result
+type dnsRecord struct {
+	Name  string `json:"name"`
+	TTL   int    `json:"ttl"`
+	Class string `json:"class"`
+	Type  string `json:"type"`
+	Data  string `json:"data"`
+}
+
+// digReport is dig result with typed DNS records of every section
+type digReport struct {
+	Hostname   string      `json:"hostname"`
+	QueryType  string      `json:"queryType"`
+	Answer     []dnsRecord `json:"answer"`
+	Authority  []dnsRecord `json:"authority,omitempty"`
+	Additional []dnsRecord `json:"additional,omitempty"`
+}
+
+func (r digReport) tableHeader() []string {
+	return []string{"section", "name", "ttl", "class", "type", "data"}
+}
+
+func (r digReport) tableRows() [][]string {
+	var rows [][]string
+
+	for _, section := range []struct {
+		name    string
+		records []dnsRecord
+	}{
+		{"answer", r.Answer},
+		{"authority", r.Authority},
+		{"additional", r.Additional},
+	} {
+		for _, rr := range section.records {
+			rows = append(rows, []string{section.name, rr.Name, strconv.Itoa(rr.TTL), rr.Class, rr.Type, rr.Data})
+		}
+	}
+
+	return rows
+}
+
+// summary returns answer as compact 'name -> value' lines
+func (r digReport) summary() digSummary {
+	lines := digSummary{}
+	for _, rr := range r.Answer {
+		lines = append(lines, fmt.Sprintf("%s -> %s", rr.Name, rr.Data))
+	}
+
+	return lines
+}
+
+// addresses returns A and AAAA records of answer, CNAME chain leading to them is left out
+func (r digReport) addresses() []string {
+	var addresses []string
+	for _, rr := range r.Answer {
+		if rr.Type == "A" || rr.Type == "AAAA" {
+			addresses = append(addresses, rr.Data)
+		}
+	}
+
+	return addresses
+}
+
+// digSummary is compact form of dig answer, one 'name -> value' line per record
+type digSummary []string
+
+func (s digSummary) tableHeader() []string {
+	return []string{"answer"}
+}
+
+func (s digSummary) tableRows() [][]string {
+	var rows [][]string
+	for _, line := range s {
+		rows = append(rows, []string{line})
+	}
+
+	return rows
+}
+
+// digOutputFlags returns flags shaping dig output
+func digOutputFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:  "answer-only",
+			Usage: "Show only answer section, without authority and additional records",
+		},
+		cli.BoolFlag{
+			Name:  "summary",
+			Usage: "Show answer as compact 'name -> value' lines",
+		},
+	}
+}
+
+// newDigReport converts API response into dig report. Sections are parsed
+// from dig text in 'result' when it is present, as only it carries additional
+// section, otherwise typed sections of response are used.
+func newDigReport(response *service.DigResult) digReport {
+	dig := response.DigInfo
+
+	report := digReport{Hostname: dig.Hostname, QueryType: dig.QueryType}
+
+	if dig.Result != "" {
+		report.Answer, report.Authority, report.Additional = parseDigSections(dig.Result)
+	}
+
+	if len(report.Answer) == 0 && len(report.Authority) == 0 {
+		for _, rr := range dig.AnswerSection {
+			report.Answer = append(report.Answer, dnsRecord{rr.Domain, rr.TTL, rr.RecordClass, rr.RecordType, digData(rr.PreferenceValues, rr.Value)})
+		}
+		for _, rr := range dig.AuthoritySection {
+			report.Authority = append(report.Authority, dnsRecord{rr.Domain, rr.TTL, rr.RecordClass, rr.RecordType, digData(rr.PreferenceValues, rr.Value)})
+		}
+	}
+
+	if report.Answer == nil {
+		report.Answer = []dnsRecord{}
+	}
+
+	return report
+}
+
+// digData joins MX preference with value the way dig shows it
+func digData(preference interface{}, value string) string {
+	if preference == nil || preference == "" {
+		return value
+	}
+
+	return fmt.Sprintf("%v %s", preference, value)
+}
+
+// parseDigSections reads answer, authority and additional records from dig text output
+func parseDigSections(text string) (answer, authority, additional []dnsRecord) {
+	var section *[]dnsRecord
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(line, ";; ANSWER SECTION"):
+			section = &answer
+			continue
+		case strings.HasPrefix(line, ";; AUTHORITY SECTION"):
+			section = &authority
+			continue
+		case strings.HasPrefix(line, ";; ADDITIONAL SECTION"):
+			section = &additional
+			continue
+		case line == "" || strings.HasPrefix(line, ";"):
+			// Blank line or comment ends the section
+			section = nil
+			continue
+		}
+
+		if section == nil {
+			continue
+		}
+
+		if rr, ok := parseDNSRecord(line); ok {
+			*section = append(*section, rr)
+		}
+	}
+
+	return answer, authority, additional
+}
+
+// parseDNSRecord parses 'name ttl class type data' line of dig output
+func parseDNSRecord(line string) (dnsRecord, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 {
+		return dnsRecord{}, false
+	}
+
+	ttl, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return dnsRecord{}, false
+	}
+
+	return dnsRecord{
+		Name:  fields[0],
+		TTL:   ttl,
+		Class: fields[2],
+		Type:  fields[3],
+		Data:  strings.Join(fields[4:], " "),
+	}, true
+}
+
+// digOutput builds dig report shaped by --answer-only and --summary
+func digOutput(response *service.DigResult, f flagSource) interface{} {
+	report := newDigReport(response)
+
+	if f.Bool("summary") {
+		return report.summary()
+	}
+
+	if f.Bool("answer-only") {
+		report.Authority = nil
+		report.Additional = nil
+	}
+
+	return report
+}
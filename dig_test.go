@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const digText = `; <<>> DiG 9.10.6 <<>> www.example.com A
+;; global options: +cmd
+;; Got answer:
+;; ->>HEADER<<- opcode: QUERY, status: NOERROR, id: 4242
+
+;; QUESTION SECTION:
+;www.example.com.		IN	A
+
+;; ANSWER SECTION:
+www.example.com.	300	IN	CNAME	www.example.com.edgekey.net.
+e1234.a.akamaiedge.net.	20	IN	A	23.15.7.10
+
+;; AUTHORITY SECTION:
+a.akamaiedge.net.	4000	IN	NS	n0a.akamaiedge.net.
+
+;; ADDITIONAL SECTION:
+n0a.akamaiedge.net.	4000	IN	A	88.221.81.192
+
+;; Query time: 12 msec
+`
+
+func TestParseDigSections(t *testing.T) {
+	answer, authority, additional := parseDigSections(digText)
+
+	wantAnswer := []dnsRecord{
+		{Name: "www.example.com.", TTL: 300, Class: "IN", Type: "CNAME", Data: "www.example.com.edgekey.net."},
+		{Name: "e1234.a.akamaiedge.net.", TTL: 20, Class: "IN", Type: "A", Data: "23.15.7.10"},
+	}
+	wantAuthority := []dnsRecord{{Name: "a.akamaiedge.net.", TTL: 4000, Class: "IN", Type: "NS", Data: "n0a.akamaiedge.net."}}
+	wantAdditional := []dnsRecord{{Name: "n0a.akamaiedge.net.", TTL: 4000, Class: "IN", Type: "A", Data: "88.221.81.192"}}
+
+	if !reflect.DeepEqual(answer, wantAnswer) {
+		t.Errorf("answer = %+v, want %+v", answer, wantAnswer)
+	}
+	if !reflect.DeepEqual(authority, wantAuthority) {
+		t.Errorf("authority = %+v, want %+v", authority, wantAuthority)
+	}
+	if !reflect.DeepEqual(additional, wantAdditional) {
+		t.Errorf("additional = %+v, want %+v", additional, wantAdditional)
+	}
+}
+
+func TestDigOutput(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name:     "typed records",
+			args:     []string{"ip", "dig", "--hostname", "www.example.com", "23.15.7.10"},
+			contains: []string{`"answer": [`, `"name": "www.example.com."`, `"ttl": 300`, `"type": "CNAME"`, `"data": "www.example.com.edgekey.net."`},
+		},
+		{
+			name:     "summary",
+			args:     []string{"ghost", "dig", "--hostname", "www.example.com", "--summary", "paris-france"},
+			contains: []string{`"www.example.com. -> www.example.com.edgekey.net."`},
+		},
+		{
+			name:     "table",
+			args:     []string{"--output", "table", "ghost", "dig", "--hostname", "www.example.com", "--answer-only", "paris-france"},
+			contains: []string{"SECTION", "answer", "CNAME"},
+		},
+	})
+}
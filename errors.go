@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	service "github.com/apiheat/go-edgegrid/v6/service/diagnosticv2"
+	"github.com/urfave/cli"
+)
+
+// Exit codes are part of the tool contract and must not change, automation
+// relies on them. Codes 3, 4 and 5 keep meaning they had before errors were
+// classified. Keep README in sync when adding new ones.
+const (
+	exitSuccess          = 0
+	exitFailure          = 1
+	exitCheckFailed      = 2
+	exitValidation       = 3
+	exitInvalidFlag      = 4
+	exitInvalidQueryType = 5
+	exitAPI              = 6
+	exitTimeout          = 7
+	exitAuth             = 8
+	exitNotFound         = 9
+)
+
+// validationError is returned when user provided arguments or flags are not
+// correct. Code tells which of validation exit codes is used, exitValidation
+// when it is not set.
+type validationError struct {
+	msg  string
+	code int
+}
+
+func (e validationError) Error() string {
+	return e.msg
+}
+
+// authError is returned when credentials cannot be loaded or API refuses them
+type authError struct {
+	err error
+}
+
+func (e authError) Error() string {
+	return e.err.Error()
+}
+
+// apiError is returned when Diagnostic Tools API call fails
+type apiError struct {
+	err error
+}
+
+func (e apiError) Error() string {
+	return e.err.Error()
+}
+
+// notFoundError is returned when requested object does not exist
+type notFoundError struct {
+	err error
+}
+
+func (e notFoundError) Error() string {
+	return e.err.Error()
+}
+
+// checkFailedError is returned when command ran, but checks it made found a problem
+type checkFailedError struct {
+	msg string
+}
+
+func (e checkFailedError) Error() string {
+	return e.msg
+}
+
+// timeoutError is returned when operation did not finish in time.
+// RequestID is set when the operation can be resumed later.
+type timeoutError struct {
+	msg       string
+	RequestID string
+}
+
+func (e timeoutError) Error() string {
+	if e.RequestID == "" {
+		return e.msg
+	}
+
+	return fmt.Sprintf("%s, request ID: %s", e.msg, e.RequestID)
+}
+
+func newValidationError(format string, a ...interface{}) error {
+	return validationError{msg: fmt.Sprintf(format, a...)}
+}
+
+// newFlagError is validation error of missing or invalid flag value
+func newFlagError(format string, a ...interface{}) error {
+	return validationError{msg: fmt.Sprintf(format, a...), code: exitInvalidFlag}
+}
+
+// newQueryTypeError is validation error of unsupported DNS query type
+func newQueryTypeError(format string, a ...interface{}) error {
+	return validationError{msg: fmt.Sprintf(format, a...), code: exitInvalidQueryType}
+}
+
+// newAPIError classifies error returned by diagnosticv2 client
+func newAPIError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var status int64
+	switch e := err.(type) {
+	case *service.DiagnosticErrorv2:
+		status = e.Status
+	case service.DiagnosticErrorv2:
+		status = e.Status
+	case validationError, authError, apiError, notFoundError, timeoutError, checkFailedError:
+		return err
+	}
+
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return authError{err: err}
+	case http.StatusNotFound:
+		return notFoundError{err: err}
+	}
+
+	return apiError{err: err}
+}
+
+// exitCode maps error returned from command action into exit code of the tool
+func exitCode(err error) int {
+	switch e := err.(type) {
+	case nil:
+		return exitSuccess
+	case validationError:
+		if e.code != 0 {
+			return e.code
+		}
+
+		return exitValidation
+	case authError:
+		return exitAuth
+	case notFoundError:
+		return exitNotFound
+	case apiError:
+		return exitAPI
+	case timeoutError:
+		return exitTimeout
+	case checkFailedError:
+		return exitCheckFailed
+	}
+
+	return exitFailure
+}
+
+// errorSeverity ranks error classes, the higher the more severe
+func errorSeverity(err error) int {
+	switch err.(type) {
+	case authError:
+		return 6
+	case apiError:
+		return 5
+	case timeoutError:
+		return 4
+	case notFoundError:
+		return 3
+	case checkFailedError:
+		return 2
+	case validationError:
+		return 1
+	}
+
+	return 0
+}
+
+// failable is implemented by results of command executed for many targets,
+// which records failures of single targets instead of returning them
+type failable interface {
+	failure() error
+}
+
+// failureOf returns error of result which failed for every target, so exit
+// code tells nothing succeeded. Partial failures are reported only in result.
+func failureOf(result interface{}) error {
+	if f, ok := result.(failable); ok {
+		return f.failure()
+	}
+
+	return nil
+}
+
+// allFailed returns the most severe of errs when there is one for each of
+// total records, nil otherwise
+func allFailed(errs []error, total int) error {
+	if total == 0 || len(errs) < total {
+		return nil
+	}
+
+	worst := errs[0]
+	for _, err := range errs[1:] {
+		if errorSeverity(err) > errorSeverity(worst) {
+			worst = err
+		}
+	}
+
+	return worst
+}
+
+// requireArgument returns first command argument or validation error when it is missing
+func requireArgument(c *cli.Context, errMessage string) (string, error) {
+	if c.NArg() == 0 {
+		return "", validationError{msg: errMessage}
+	}
+
+	return c.Args().Get(0), nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	service "github.com/apiheat/go-edgegrid/v6/service/diagnosticv2"
+)
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"unauthorized", &service.DiagnosticErrorv2{Status: 401}, exitAuth},
+		{"forbidden", &service.DiagnosticErrorv2{Status: 403}, exitAuth},
+		{"not found", &service.DiagnosticErrorv2{Status: 404}, exitNotFound},
+		{"server error", &service.DiagnosticErrorv2{Status: 500}, exitAPI},
+		{"value error", service.DiagnosticErrorv2{Status: 404}, exitNotFound},
+		{"transport error", errors.New("connection refused"), exitAPI},
+		{"validation error is kept", newValidationError("bad"), exitValidation},
+		{"timeout error is kept", timeoutError{msg: "too long"}, exitTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(newAPIError(tt.err)); got != tt.want {
+				t.Errorf("exitCode(newAPIError()) = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if newAPIError(nil) != nil {
+		t.Error("newAPIError(nil) should be nil")
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if got := exitCode(nil); got != exitSuccess {
+		t.Errorf("exitCode(nil) = %d", got)
+	}
+
+	if got := exitCode(errors.New("boom")); got != exitFailure {
+		t.Errorf("exitCode(generic) = %d", got)
+	}
+}
+
+func TestAllFailed(t *testing.T) {
+	validation := newValidationError("bad")
+	api := apiError{err: errors.New("boom")}
+	auth := authError{err: errors.New("denied")}
+	check := checkFailedError{msg: "unreachable"}
+
+	tests := []struct {
+		name  string
+		errs  []error
+		total int
+		want  error
+	}{
+		{"no records", nil, 0, nil},
+		{"some succeeded", []error{api}, 2, nil},
+		{"single class", []error{validation, validation}, 2, validation},
+		{"most severe", []error{validation, auth, api}, 3, auth},
+		{"failed check over validation", []error{validation, check}, 2, check},
+		{"api error over failed check", []error{check, api}, 2, api},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allFailed(tt.errs, tt.total); got != tt.want {
+				t.Errorf("allFailed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
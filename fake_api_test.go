@@ -0,0 +1,368 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+
+	edgegrid "github.com/apiheat/go-edgegrid/v6/edgegrid"
+)
+
+// Values recognised by fake API to simulate failures
+const (
+	fakeMissing   = "missing"
+	fakeForbidden = "forbidden"
+	fakeBroken    = "broken"
+	fakePending   = "pending"
+	fakeThrottled = "throttled"
+	fakeLossy     = "lossy"
+)
+
+// fakeBrokenReference is reference string, translation of which fails, as
+// reference strings cannot hold failure markers
+const fakeBrokenReference = "50.0bad0bad.1700000000.bad"
+
+// fakeFirewalled is host and fakeFirewalledIP is GTM target which mtr from
+// fakeOutlierLocation does not reach
+const (
+	fakeFirewalled   = "firewalled"
+	fakeFirewalledIP = "192.0.2.21"
+)
+
+// fakeDownDomain is GTM domain which property fakeDownProperty has target
+// fakeDownIP, which does not respond to curl nor mtr from
+// fakeOutlierLocation, and property fakeBrokenProperty has target
+// fakeBrokenIP, curl and mtr to which fail with API error
+const (
+	fakeDownDomain     = "down.akadns.net"
+	fakeDownProperty   = "db"
+	fakeDownIP         = "192.0.2.30"
+	fakeBrokenProperty = "cache"
+	fakeBrokenIP       = "192.0.2.31"
+)
+
+// fakeEdgeIP and fakeEdgeIPv6 are addresses hostnames resolve to
+const (
+	fakeEdgeIP   = "23.15.7.10"
+	fakeEdgeIPv6 = "2a02:26f0:d8::17d4:9d1"
+)
+
+// fakeOutlierLocation returns different dig, curl and mtr results than other locations
+const fakeOutlierLocation = "tokyo-japan"
+
+const fakeBasePath = "/diagnostic-tools/v2/"
+
+// fakeAPI is local stand-in for Diagnostic Tools v2 endpoints
+type fakeAPI struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []string
+	curls    []curlRequest
+	launches map[string]int
+}
+
+func newFakeAPI() *fakeAPI {
+	api := &fakeAPI{launches: map[string]int{}}
+	api.Server = httptest.NewServer(http.HandlerFunc(api.serve))
+
+	return api
+}
+
+// client returns real diagnosticv2 client pointed at fake API
+func (api *fakeAPI) client() diagnosticClient {
+	return api.clientFor("")
+}
+
+// clientFor returns client pointed at fake API which switches to given account
+func (api *fakeAPI) clientFor(accountSwitchKey string) diagnosticClient {
+	creds := &edgegrid.Credentials{
+		Host:         "akab-fake.luna.akamaiapis.net",
+		ClientToken:  "akab-client-token",
+		ClientSecret: "client-secret",
+		AccessToken:  "akab-access-token",
+	}
+
+	config := edgegrid.NewConfig().
+		WithCredentials(creds).
+		WithLocalTesting(true).
+		WithTestingURL(api.URL).
+		WithAccountSwitchKey(accountSwitchKey).
+		WithLogVerbosity("fatal")
+
+	return newDiagnosticClient(config)
+}
+
+// calls returns all requests received so far as "METHOD path"
+func (api *fakeAPI) calls() []string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	return append([]string{}, api.requests...)
+}
+
+// curlRequests returns bodies of all curl requests received so far
+func (api *fakeAPI) curlRequests() []curlRequest {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	return append([]curlRequest{}, api.curls...)
+}
+
+func (api *fakeAPI) serve(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	api.requests = append(api.requests, r.Method+" "+r.URL.Path)
+	api.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, fakeBasePath), "/")
+
+	body, _ := ioutil.ReadAll(r.Body)
+
+	// Failures are triggered by path segment, account switch key or by first label of tested host
+	markers := append(append([]string{}, parts...), r.URL.Query().Get("accountSwitchKey"))
+	var curl curlRequest
+	if json.Unmarshal(body, &curl) == nil && curl.URL != "" {
+		api.mu.Lock()
+		api.curls = append(api.curls, curl)
+		api.mu.Unlock()
+	}
+	for _, host := range []string{r.URL.Query().Get("hostName"), r.URL.Query().Get("destinationDomain"), hostOf(curl.URL)} {
+		markers = append(markers, strings.Split(host, ".")[0])
+		if host == fakeBrokenIP {
+			markers = append(markers, fakeBroken)
+		}
+	}
+
+	for _, part := range markers {
+		switch part {
+		case fakeMissing:
+			writeFakeError(w, http.StatusNotFound, "Not Found")
+			return
+		case fakeForbidden:
+			writeFakeError(w, http.StatusForbidden, "Forbidden")
+			return
+		case fakeBroken:
+			writeFakeError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+	}
+
+	switch {
+	case r.URL.Path == fakeBasePath+"ghost-locations/available":
+		fmt.Fprint(w, fakeGhostLocations)
+	case len(parts) == 3 && parts[2] == "dig-info":
+		// fakeOutlierLocation sees different answers than the rest of the world
+		cname := "www.example.com.edgekey.net."
+		if parts[1] == fakeOutlierLocation {
+			cname = "www.example.com.edgesuite.net."
+		}
+		// AAAA queries are answered with IPv6 address of the same edge server
+		recordType, address := "A", fakeEdgeIP
+		if r.URL.Query().Get("queryType") == "AAAA" {
+			recordType, address = "AAAA", fakeEdgeIPv6
+		}
+		fmt.Fprintf(w, fakeDigInfo, r.URL.Query().Get("hostName"), r.URL.Query().Get("queryType"), cname, recordType, address)
+	case len(parts) == 3 && parts[2] == "mtr-data":
+		destination := r.URL.Query().Get("destinationDomain")
+		if parts[1] == fakeOutlierLocation && (strings.HasPrefix(destination, fakeFirewalled+".") || destination == fakeFirewalledIP || destination == fakeDownIP) {
+			fmt.Fprintf(w, fakeUnreachableMtrData, parts[1], destination)
+			return
+		}
+		if strings.HasPrefix(destination, fakeLossy+".") {
+			fmt.Fprintf(w, fakeLossyMtrData, parts[1], destination)
+			return
+		}
+		fmt.Fprintf(w, fakeMtrData, parts[1], destination)
+	case len(parts) == 3 && parts[2] == "curl-results":
+		// Request is echoed back in headers, so tests can check what was sent
+		extraHeaders := fmt.Sprintf(`, "X-Fake-User-Agent": %q, "X-Fake-Request-Headers": %q, "X-Fake-Method": %q`, curl.UserAgent, strings.Join(curl.RequestHeaders, " | "), curl.Method)
+		if parts[1] == fakeOutlierLocation {
+			extraHeaders += `, "ETag": "\"v1\""`
+		} else {
+			extraHeaders += `, "ETag": "\"v2\""`
+		}
+		for _, header := range curl.RequestHeaders {
+			if strings.HasPrefix(header, "Pragma: akamai-x-cache-on") {
+				// fakeOutlierLocation reports different cache status with the same outcome
+				debugHeaders := fakeDebugHeaders
+				if parts[1] == fakeOutlierLocation {
+					debugHeaders = strings.Replace(debugHeaders, "TCP_MISS", "TCP_REFRESH_MISS", 1)
+				}
+				extraHeaders += debugHeaders
+			}
+		}
+		status := http.StatusOK
+		if parts[1] == fakeOutlierLocation && hostOf(curl.URL) == fakeDownIP {
+			status = http.StatusGatewayTimeout
+		}
+		fmt.Fprintf(w, fakeCurlResults, status, curl.URL, extraHeaders)
+	case len(parts) == 3 && parts[2] == "is-cdn-ip":
+		fmt.Fprintf(w, `{"isCdnIp": %t}`, strings.HasPrefix(parts[1], "23.") || strings.HasPrefix(parts[1], "2a02:26f0:"))
+	case len(parts) == 3 && parts[2] == "geo-location":
+		fmt.Fprintf(w, fakeGeoLocation, parts[1])
+	case len(parts) == 3 && parts[0] == "errors" && parts[2] == "translate-error":
+		api.mu.Lock()
+		api.launches[parts[1]]++
+		first := api.launches[parts[1]] == 1
+		api.mu.Unlock()
+
+		if parts[1] == fakeBrokenReference {
+			writeFakeError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		// The first launch of fakeThrottled is rate limited
+		if parts[1] == fakeThrottled && first {
+			w.Header().Set("Retry-After", "0")
+			writeFakeError(w, http.StatusTooManyRequests, "Too Many Requests")
+			return
+		}
+		fmt.Fprintf(w, `{"requestId": "req-%s", "link": "/diagnostic-tools/v2/translate-error-requests/req-%s", "retryAfter": 0}`, parts[1], parts[1])
+	case len(parts) == 2 && parts[0] == "translate-error-requests":
+		retryAfter := 0
+		if strings.Contains(parts[1], fakePending) {
+			retryAfter = 1
+		}
+		fmt.Fprintf(w, `{"requestId": "%s", "link": "/diagnostic-tools/v2/translate-error-requests/%s", "retryAfter": %d}`, parts[1], parts[1], retryAfter)
+	case len(parts) == 3 && parts[0] == "translate-error-requests" && parts[2] == "translated-error":
+		fmt.Fprint(w, fakeTranslatedError)
+	case r.URL.Path == fakeBasePath+"gtm/gtm-properties":
+		fmt.Fprint(w, fakeGTMProperties)
+	case len(parts) == 4 && parts[0] == "gtm" && parts[3] == "gtm-property-ips":
+		targets := `"192.0.2.20", "` + fakeFirewalledIP + `"`
+		switch parts[1] {
+		case fakeDownProperty:
+			targets = `"` + fakeDownIP + `"`
+		case fakeBrokenProperty:
+			targets = `"` + fakeBrokenIP + `"`
+		}
+		fmt.Fprintf(w, fakeGTMPropertyIPs, parts[1], parts[2], targets)
+	case r.URL.Path == fakeBasePath+"end-users/diagnostic-url":
+		fmt.Fprint(w, `{"diagnosticUrl": "https://fake.akamai.com/diagnostic/abc"}`)
+	case r.URL.Path == fakeBasePath+"end-users/ip-requests":
+		fmt.Fprint(w, fakeLinkRequests)
+	case len(parts) == 4 && parts[0] == "end-users" && parts[3] == "ip-details":
+		fmt.Fprint(w, fakeLinkDetails)
+	default:
+		writeFakeError(w, http.StatusNotFound, "Unknown endpoint")
+	}
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return u.Hostname()
+}
+
+func writeFakeError(w http.ResponseWriter, status int, title string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"type": "fake", "title": "%s", "status": %d, "detail": "fake API failure"}`, title, status)
+}
+
+const fakeGhostLocations = `{"locations": [
+	{"id": "frankfurt-germany", "value": "Frankfurt, Germany"},
+	{"id": "berlin-germany", "value": "Berlin, Germany"},
+	{"id": "paris-france", "value": "Paris, France"},
+	{"id": "tokyo-japan", "value": "Tokyo, Japan"}
+]}`
+
+const fakeDigInfo = `{"digInfo": {
+	"hostname": "%s",
+	"queryType": "%s",
+	"answerSection": [{"domain": "www.example.com.", "ttl": 300, "recordClass": "IN", "recordType": "CNAME", "value": "%s"},
+		{"domain": "%[3]s", "ttl": 20, "recordClass": "IN", "recordType": "%[4]s", "value": "%[5]s"}],
+	"authoritySection": [],
+	"result": ""
+}}`
+
+const fakeMtrData = `{"mtr": {
+	"source": "%s",
+	"destination": "%s",
+	"packetLoss": 0,
+	"avgLatency": 10.5,
+	"hops": [
+		{"number": 1, "host": "10.0.0.1", "loss": 0, "sent": 10, "last": 0.5, "avg": 0.5, "best": 0.4, "worst": 0.7, "stDev": 0.1},
+		{"number": 2, "host": "192.0.2.1", "loss": 0, "sent": 10, "last": 10.4, "avg": 10.5, "best": 10.1, "worst": 11.2, "stDev": 0.3}
+	],
+	"result": ""
+}}`
+
+const fakeUnreachableMtrData = `{"mtr": {
+	"source": "%s",
+	"destination": "%s",
+	"packetLoss": 50,
+	"avgLatency": 0.5,
+	"hops": [
+		{"number": 1, "host": "10.0.0.1", "loss": 0, "sent": 10, "last": 0.5, "avg": 0.5, "best": 0.4, "worst": 0.7, "stDev": 0.1},
+		{"number": 2, "host": "???", "loss": 100, "sent": 10, "last": 0, "avg": 0, "best": 0, "worst": 0, "stDev": 0}
+	],
+	"result": ""
+}}`
+
+// fakeLossyMtrData has hops in text report only, so packet loss is known from them only
+const fakeLossyMtrData = `{"mtr": {
+	"source": "%s",
+	"destination": "%s",
+	"packetLoss": 0,
+	"avgLatency": 0,
+	"hops": [],
+	"result": "HOST: ghost Loss%%   Snt   Last   Avg  Best  Wrst StDev\n  1.|-- 10.0.0.1   0.0%%    10    0.5   0.5   0.4   0.7   0.1\n  2.|-- 192.0.2.1  30.0%%    10   10.4  10.5  10.1  11.2   0.3\n  3.|-- 192.0.2.2   0.0%%    10   12.0  12.1  11.8  13.0   0.2\n"
+}}`
+
+const fakeCurlResults = `{"curlResults": {
+	"httpStatusCode": %d,
+	"responseHeaders": {"Server": "AkamaiGHost", "Content-Length": "42", "Content-Type": "text/html", "server-timing": "cdn-cache; desc=MISS, edge; dur=12, origin; dur=35", "Url": "%s"%s},
+	"responseBody": "<html></html>"
+}}`
+
+// fakeDebugHeaders are returned by curl when Akamai debug Pragma is sent
+const fakeDebugHeaders = `,
+		"X-Cache": "TCP_MISS from a23-15-7-10.deploy.akamaitechnologies.com (AkamaiGHost/10.0.0-1) (-)",
+		"X-Check-Cacheable": "YES",
+		"X-Cache-Key": "S/L/1234/567890/1d/origin.example.com/index.html",
+		"X-True-Cache-Key": "/L/www.example.com/index.html",
+		"X-Serial": "1234"`
+
+const fakeGeoLocation = `{"geoLocation": {"clientIp": "%s", "countryCode": "DE", "city": "FRANKFURT", "latitude": 50.12, "longitude": 8.68, "continent": "EU"}}`
+
+const fakeTranslatedError = `{"translatedError": {
+	"url": "https://www.example.com/",
+	"httpResponseCode": 503,
+	"timestamp": "Fri Oct 18 08:00:00 GMT 2026",
+	"serverIp": "23.15.7.10",
+	"originHostname": "origin.example.com",
+	"reasonForFailure": "Connection to origin server timed out",
+	"logs": []
+}}`
+
+const fakeGTMProperties = `{"gtmProperties": [
+	{"property": "www", "domain": "example.akadns.net", "hostName": "www.example.akadns.net"},
+	{"property": "api", "domain": "example.akadns.net", "hostName": "api.example.akadns.net"},
+	{"property": "db", "domain": "down.akadns.net", "hostName": "db.down.akadns.net"},
+	{"property": "cache", "domain": "down.akadns.net", "hostName": "cache.down.akadns.net"}
+]}`
+
+const fakeGTMPropertyIPs = `{"gtmPropertyIps": {"property": "%s", "domain": "%s", "testIps": ["192.0.2.10"], "targetIps": [%s]}}`
+
+const fakeLinkRequests = `{"endUserIpRequests": [
+	{"name": "beloved-customer", "requestId": 1234, "url": "https://www.example.com/", "timestamp": "2026-10-01T10:00:00Z"}
+]}`
+
+const fakeLinkDetails = `{"endUserIpDetails": {
+	"name": "beloved-customer",
+	"timestamp": "2026-10-01T10:00:00Z",
+	"url": "https://www.example.com/",
+	"ips": [{"description": "Client IP", "location": "Frankfurt, Germany", "ip": "198.51.100.7", "ipType": "IPv4"}],
+	"browser": "Firefox"
+}}`
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	common "github.com/apiheat/akamai-cli-common"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const allGhostLocations = "all"
+
+// locationResult holds outcome of a command executed from single ghost location
+type locationResult struct {
+	Location string      `json:"location"`
+	Result   interface{} `json:"result,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	err      error
+}
+
+// locationReport is the merged outcome of a command executed from many
+// ghost locations, keyed by location
+type locationReport map[string]locationResult
+
+func (r locationReport) tableHeader() []string {
+	return []string{"location", "status", "error", "result"}
+}
+
+func (r locationReport) tableRows() [][]string {
+	var rows [][]string
+	for _, location := range r.locations() {
+		res := r[location]
+
+		status, result := "ok", outputJSON(res.Result)
+		if res.Error != "" {
+			status, result = "failed", ""
+		}
+
+		rows = append(rows, []string{location, status, res.Error, result})
+	}
+
+	return rows
+}
+
+func (r locationReport) failure() error {
+	var errs []error
+	for _, res := range r {
+		if res.err != nil {
+			errs = append(errs, res.err)
+		}
+	}
+
+	return allFailed(errs, len(r))
+}
+
+func (r locationReport) locations() []string {
+	var locations []string
+	for location := range r {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+
+	return locations
+}
+
+// ghostLocationFlags are shared by ghost commands which can be run from many locations
+func ghostLocationFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "locations",
+			Value: "",
+			Usage: "Comma separated list of ghost `LOCATIONS` to run from instead of GHOST_LOCATION argument. Each entry can be location ID, glob like '*Germany*' or 'all'",
+		},
+		workersFlag(),
+	}
+}
+
+// runOnGhostLocations executes fn either for GHOST_LOCATION argument (or
+// locations read with 'input' flag), for location nearest to IP given with
+// 'near' flag or, when 'locations' flag is provided, concurrently for every
+// matching location. Flags are checked with validate once before fn runs,
+// only rows of input, which can override flags, are checked one by one.
+func runOnGhostLocations(c *cli.Context, validate func(f flagSource) error, fn func(location string, flags flagSource) (interface{}, error)) error {
+	if c.String("input") == "" {
+		if err := validate(c); err != nil {
+			return err
+		}
+	}
+
+	if c.String("near") != "" {
+		if c.String("locations") != "" || c.String("input") != "" || c.NArg() > 0 {
+			return newValidationError("Please use --near alone, without GHOST_LOCATION, --locations or --input")
+		}
+
+		nearest, err := findNearestLocation(c.String("near"))
+		if err != nil {
+			return err
+		}
+
+		log.Infof("Running from %s, location nearest to %s by %s", nearest.Location.ID, nearest.IP, nearest.Match)
+
+		result, err := fn(nearest.Location.ID, c)
+		if err != nil {
+			return newAPIError(err)
+		}
+
+		return printOutput(c, result)
+	}
+
+	if c.String("locations") == "" {
+		return runOnTargets(c, "Please provide Ghost Location Name or use --locations", func(location string, f flagSource) (interface{}, error) {
+			if err := validateGhostLocation(location); err != nil {
+				return nil, err
+			}
+
+			if err := validate(f); err != nil {
+				return nil, err
+			}
+
+			return fn(location, f)
+		})
+	}
+
+	if c.String("input") != "" {
+		return newValidationError("Please use either --locations or --input, not both")
+	}
+
+	locations, err := resolveGhostLocations(c.String("locations"))
+	if err != nil {
+		return err
+	}
+
+	report := fanOut(locations, c.Int("workers"), func(location string) (interface{}, error) {
+		return fn(location, c)
+	})
+
+	if err := printOutput(c, report); err != nil {
+		return err
+	}
+
+	return report.failure()
+}
+
+// resolveGhostLocations expands user provided locations specification into location IDs
+func resolveGhostLocations(spec string) ([]string, error) {
+	var patterns, locations []string
+
+	for _, item := range common.StringToStringsArr(spec) {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+			continue
+		case item == allGhostLocations || strings.ContainsAny(item, "*?["):
+			patterns = append(patterns, item)
+		default:
+			locations = append(locations, item)
+		}
+	}
+
+	for _, location := range locations {
+		if err := validateGhostLocation(location); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(patterns) > 0 {
+		known, _, err := knownGhostLocations(false)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pattern := range patterns {
+			matched := 0
+			for _, location := range known {
+				ok, err := matchLocation(pattern, location.ID, location.Value)
+				if err != nil {
+					return nil, newValidationError("Invalid location pattern '%s': %s", pattern, err)
+				}
+
+				if ok {
+					locations = append(locations, location.ID)
+					matched++
+				}
+			}
+
+			log.Debugf("Location pattern '%s' matched %d locations", pattern, matched)
+		}
+	}
+
+	locations = common.RemoveStringDuplicates(locations)
+	if len(locations) == 0 {
+		return nil, newValidationError("No ghost locations match '%s'", spec)
+	}
+
+	return locations, nil
+}
+
+// selectGhostLocations resolves --locations or, without it, picks one ghost
+// location per continent
+func selectGhostLocations(c *cli.Context) ([]string, error) {
+	if c.String("locations") != "" {
+		return resolveGhostLocations(c.String("locations"))
+	}
+
+	known, _, err := knownGhostLocations(false)
+	if err != nil {
+		return nil, err
+	}
+
+	locations := locationPerContinent(known)
+	if len(locations) == 0 {
+		return nil, newValidationError("No ghost locations available, please provide --locations")
+	}
+
+	return locations, nil
+}
+
+// locationPerContinent picks the first location by ID from every continent.
+// When continent of no location is known, the first location is picked.
+func locationPerContinent(known []ghostLocation) []string {
+	sorted := append([]ghostLocation{}, known...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	picked := map[string]string{}
+	for _, l := range sorted {
+		if _, ok := picked[l.Continent]; !ok && l.Continent != "" {
+			picked[l.Continent] = l.ID
+		}
+	}
+
+	var continents, locations []string
+	for continent := range picked {
+		continents = append(continents, continent)
+	}
+	sort.Strings(continents)
+
+	for _, continent := range continents {
+		locations = append(locations, picked[continent])
+	}
+
+	if len(locations) == 0 && len(sorted) > 0 {
+		locations = append(locations, sorted[0].ID)
+	}
+
+	return locations
+}
+
+func matchLocation(pattern, id, value string) (bool, error) {
+	if pattern == allGhostLocations {
+		return true, nil
+	}
+
+	pattern = strings.ToLower(pattern)
+	for _, candidate := range []string{id, value} {
+		ok, err := path.Match(pattern, strings.ToLower(candidate))
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+
+	return false, nil
+}
+
+// fanOut runs fn for every location using at most workers goroutines.
+// Failure of single location is recorded in report and does not stop others.
+func fanOut(locations []string, workers int, fn func(location string) (interface{}, error)) locationReport {
+	var (
+		mu     sync.Mutex
+		report = locationReport{}
+	)
+
+	forEachConcurrently(locations, workers, func(location string) {
+		log.Debugf("Running from ghost location %s", location)
+
+		res := locationResult{Location: location}
+		result, err := fn(location)
+		if err != nil {
+			res.err = newAPIError(err)
+			res.Error = errorText(err)
+			log.Warnf("Ghost location %s failed: %s", location, res.Error)
+		} else {
+			res.Result = result
+		}
+
+		mu.Lock()
+		report[location] = res
+		mu.Unlock()
+	})
+
+	return report
+}
+
+// forEachConcurrently calls fn for every item using at most workers goroutines
+func forEachConcurrently(items []string, workers int, fn func(item string)) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		wg    sync.WaitGroup
+		queue = make(chan string)
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for item := range queue {
+				fn(item)
+			}
+		}()
+	}
+
+	for _, item := range items {
+		queue <- item
+	}
+	close(queue)
+	wg.Wait()
+}
@@ -0,0 +1,106 @@
+package main
+
+import "testing"
+
+func TestGhostCommands(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name:     "locations",
+			args:     []string{"ghost", "locations"},
+			contains: []string{`"id": "frankfurt-germany"`, `"value": "Tokyo, Japan"`},
+		},
+		{
+			name:     "dig",
+			args:     []string{"ghost", "dig", "--hostname", "www.example.com", "frankfurt-germany"},
+			contains: []string{`"hostname": "www.example.com"`},
+		},
+		{
+			name:     "dig without location",
+			args:     []string{"ghost", "dig", "--hostname", "www.example.com"},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "dig unknown location",
+			args:     []string{"ghost", "dig", "--hostname", "www.example.com", "frankfurt-germny"},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "mtr",
+			args:     []string{"ghost", "mtr", "--destination-domain", "www.example.com", "paris-france"},
+			contains: []string{`"source": "paris-france"`},
+		},
+		{
+			name:     "mtr with scheme",
+			args:     []string{"ghost", "mtr", "--destination-domain", "http://www.example.com", "paris-france"},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "curl",
+			args:     []string{"ghost", "curl", "--url", "https://www.example.com/", "tokyo-japan"},
+			contains: []string{`"httpStatusCode": 200`},
+		},
+		{
+			name:     "curl forbidden",
+			args:     []string{"ghost", "curl", "--url", "https://" + fakeForbidden + ".example.com/", "paris-france"},
+			exitCode: exitAuth,
+		},
+		{
+			name:     "dig from many locations",
+			args:     []string{"ghost", "dig", "--hostname", fakeBroken + ".example.com", "--locations", "*germany*,tokyo-japan"},
+			exitCode: exitAPI,
+			contains: []string{`"frankfurt-germany": {`, `"berlin-germany": {`, `"tokyo-japan": {`, `"error": "Internal Server Error: fake API failure"`},
+		},
+		{
+			name:     "mtr from all locations",
+			args:     []string{"ghost", "mtr", "--destination-domain", "www.example.com", "--locations", "all"},
+			contains: []string{`"source": "frankfurt-germany"`, `"source": "berlin-germany"`, `"source": "paris-france"`, `"source": "tokyo-japan"`},
+		},
+		{
+			name:     "dig from many locations without hostname",
+			args:     []string{"ghost", "dig", "--locations", "all"},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "curl from many locations without url",
+			args:     []string{"ghost", "curl", "--locations", "paris-france"},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "mtr from many locations with scheme",
+			args:     []string{"ghost", "mtr", "--destination-domain", "http://x.com", "--locations", "all"},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "dig near ip without hostname",
+			args:     []string{"ghost", "dig", "--near", "23.15.7.10"},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "locations glob without match",
+			args:     []string{"ghost", "dig", "--hostname", "www.example.com", "--locations", "*poland*"},
+			exitCode: exitValidation,
+		},
+	})
+}
+
+func TestMatchLocation(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"all", true},
+		{"*germany*", true},
+		{"*Germany*", true},
+		{"frankfurt*", true},
+		{"*france*", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got, err := matchLocation(tt.pattern, "frankfurt-germany", "Frankfurt, Germany")
+			if err != nil || got != tt.want {
+				t.Errorf("matchLocation(%q) = %t, %v, want %t", tt.pattern, got, err, tt.want)
+			}
+		})
+	}
+}
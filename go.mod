@@ -5,9 +5,11 @@ go 1.13
 require (
 	github.com/apiheat/akamai-cli-common v3.1.0+incompatible
 	github.com/apiheat/go-edgegrid/v6 v6.1.10
+	github.com/go-ini/ini v1.46.0
 	github.com/mitchellh/go-homedir v1.1.0 // indirect
 	github.com/sirupsen/logrus v1.4.2
 	github.com/smartystreets/goconvey v0.0.0-20190731233626-505e41936337 // indirect
 	github.com/urfave/cli v1.22.1
 	gopkg.in/ini.v1 v1.48.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 )
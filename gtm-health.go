@@ -0,0 +1,378 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	common "github.com/apiheat/akamai-cli-common"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+func cmdGTMHealth(c *cli.Context) error {
+	return gtmHealth(c)
+}
+
+// checkErrored is status of GTM target probe when its API calls failed, so
+// health of target is not known. It is kept apart from checkFail, which means
+// target itself did not respond.
+const checkErrored = "error"
+
+// targetProbe is outcome of curl and mtr to GTM target from single ghost location
+type targetProbe struct {
+	Location       string  `json:"location"`
+	Region         string  `json:"region"`
+	Status         string  `json:"status"`
+	HTTPStatusCode int     `json:"httpStatusCode,omitempty"`
+	CurlError      string  `json:"curlError,omitempty"`
+	Reached        bool    `json:"reached"`
+	PacketLoss     float64 `json:"packetLoss"`
+	MtrError       string  `json:"mtrError,omitempty"`
+	err            error
+}
+
+// gtmTargetHealth is status of single datacenter target of GTM property in
+// every region, together with probes it is based on
+type gtmTargetHealth struct {
+	Property string            `json:"property"`
+	Target   string            `json:"target"`
+	Regions  map[string]string `json:"regions"`
+	Probes   []targetProbe     `json:"probes"`
+}
+
+// gtmPropertyTargets are test and target IPs of GTM property
+type gtmPropertyTargets struct {
+	Property  string   `json:"property"`
+	HostName  string   `json:"hostName"`
+	TestIPs   []string `json:"testIps,omitempty"`
+	TargetIPs []string `json:"targetIps,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	err       error
+}
+
+// gtmHealthReport is matrix of GTM datacenter targets versus regions of
+// ghost locations they were probed from
+type gtmHealthReport struct {
+	Domain     string               `json:"domain"`
+	Regions    []string             `json:"regions"`
+	Properties []gtmPropertyTargets `json:"properties"`
+	Targets    []gtmTargetHealth    `json:"targets"`
+}
+
+func (r gtmHealthReport) tableHeader() []string {
+	return append([]string{"property", "target"}, r.Regions...)
+}
+
+func (r gtmHealthReport) tableRows() [][]string {
+	var rows [][]string
+	for _, t := range r.Targets {
+		row := []string{t.Property, t.Target}
+		for _, region := range r.Regions {
+			row = append(row, t.Regions[region])
+		}
+
+		rows = append(rows, row)
+	}
+
+	for _, p := range r.Properties {
+		if p.Error != "" {
+			row := []string{p.Property, "failed: " + p.Error}
+			for range r.Regions {
+				row = append(row, "")
+			}
+
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+// failure returns the most severe API error when neither IPs of any property
+// nor any target could be checked
+func (r gtmHealthReport) failure() error {
+	var errs []error
+	total := 0
+
+	for _, p := range r.Properties {
+		if p.err != nil {
+			errs = append(errs, p.err)
+			total++
+		}
+	}
+
+	for _, t := range r.Targets {
+		for _, probe := range t.Probes {
+			if probe.err != nil {
+				errs = append(errs, probe.err)
+			}
+			total++
+		}
+	}
+
+	return allFailed(errs, total)
+}
+
+// checkError returns error naming targets which failed from any region, nil
+// when none did
+func (r gtmHealthReport) checkError() error {
+	var failed []string
+	for _, t := range r.Targets {
+		for _, region := range r.Regions {
+			if t.Regions[region] == checkFail {
+				failed = append(failed, fmt.Sprintf("%s of %s from %s", t.Target, t.Property, region))
+			}
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return checkFailedError{msg: fmt.Sprintf("GTM targets failed: %s", strings.Join(failed, ", "))}
+}
+
+// gtmHealth probes datacenter targets of GTM properties of domain with curl
+// and mtr from ghost locations and reports their status per region. It fails
+// when any target fails from any region or when all API calls failed.
+func gtmHealth(c *cli.Context) error {
+	domain := c.String("domain")
+	if domain == "" {
+		return newFlagError("Provide domain, this is required parameter. The Global Traffic Management domain which properties to check")
+	}
+
+	if c.String("scheme") != "http" && c.String("scheme") != "https" {
+		return newFlagError("Provide correct 'scheme': http or https")
+	}
+
+	if !strings.HasPrefix(c.String("path"), "/") {
+		return newFlagError("'path' has to start with '/': %s", c.String("path"))
+	}
+
+	// Target URLs differ only by host, so headers are validated once
+	if err := validateCurlOptions(rowFlags{c: c, row: map[string]string{"url": targetURL(c.String("scheme"), domain, c.String("path"))}}); err != nil {
+		return err
+	}
+
+	properties, err := gtmHealthProperties(domain, c.Args())
+	if err != nil {
+		return err
+	}
+
+	locations, err := selectGhostLocations(c)
+	if err != nil {
+		return err
+	}
+
+	regions := locationRegions()
+
+	var regionNames []string
+	for _, location := range locations {
+		regionNames = append(regionNames, regionOf(regions, location))
+	}
+
+	report := gtmHealthReport{Domain: domain, Regions: common.RemoveStringDuplicates(regionNames), Properties: properties, Targets: []gtmTargetHealth{}}
+	sort.Strings(report.Regions)
+
+	for i, p := range report.Properties {
+		response, err := apiClient.ListGTMPropertyIPs(p.Property, domain)
+		if err != nil {
+			report.Properties[i].err = newAPIError(err)
+			report.Properties[i].Error = errorText(report.Properties[i].err)
+			log.Warnf("Cannot get IPs of property %s: %s", p.Property, report.Properties[i].Error)
+			continue
+		}
+
+		report.Properties[i].TestIPs = response.GtmPropertyIps.TestIps
+		report.Properties[i].TargetIPs = response.GtmPropertyIps.TargetIps
+
+		for _, target := range response.GtmPropertyIps.TargetIps {
+			log.Debugf("Probing target %s of property %s from %d locations", target, p.Property, len(locations))
+
+			report.Targets = append(report.Targets, probeGTMTarget(c, p, target, locations, regions))
+		}
+	}
+
+	if err := printOutput(c, report); err != nil {
+		return err
+	}
+
+	if err := report.failure(); err != nil {
+		return err
+	}
+
+	return report.checkError()
+}
+
+// gtmHealthProperties lists properties of domain, only those given as
+// arguments when there are any
+func gtmHealthProperties(domain string, names []string) ([]gtmPropertyTargets, error) {
+	response, err := fetchGTMProperties(apiClient)
+	if err != nil {
+		return nil, err
+	}
+
+	var properties []gtmPropertyTargets
+	var found []string
+	for _, p := range response.GtmProperties {
+		if p.Domain != domain || (len(names) > 0 && !common.IsStringInSlice(p.Property, names)) {
+			continue
+		}
+
+		properties = append(properties, gtmPropertyTargets{Property: p.Property, HostName: p.HostName})
+		found = append(found, p.Property)
+	}
+
+	for _, name := range names {
+		if !common.IsStringInSlice(name, found) {
+			return nil, notFoundError{err: fmt.Errorf("Property %s not found in domain %s", name, domain)}
+		}
+	}
+
+	if len(properties) == 0 {
+		return nil, notFoundError{err: fmt.Errorf("No properties found in domain %s", domain)}
+	}
+
+	return properties, nil
+}
+
+// probeGTMTarget runs curl and mtr to target from every location and
+// combines their statuses per region. Target is requested with host name of
+// property, as clients resolving property do.
+func probeGTMTarget(c *cli.Context, p gtmPropertyTargets, target string, locations []string, regions map[string]string) gtmTargetHealth {
+	f := rowFlags{c: c, row: map[string]string{
+		"url":    targetURL(c.String("scheme"), target, c.String("path")),
+		"header": strings.Join(withHostHeader(c.StringSlice("header"), p.HostName), "|"),
+	}}
+
+	// Probes are kept also for locations where both API calls failed, as
+	// fanOut records only error of those
+	var (
+		mu     sync.Mutex
+		probes = map[string]targetProbe{}
+	)
+
+	report := fanOut(locations, c.Int("workers"), func(location string) (interface{}, error) {
+		probe := probeGTMTargetFrom(location, regionOf(regions, location), target, f)
+
+		mu.Lock()
+		probes[location] = probe
+		mu.Unlock()
+
+		return probe, probe.err
+	})
+
+	health := gtmTargetHealth{Property: p.Property, Target: target, Regions: map[string]string{}}
+	statuses := map[string][]string{}
+	for _, location := range report.locations() {
+		probe := probes[location]
+		health.Probes = append(health.Probes, probe)
+		statuses[probe.Region] = append(statuses[probe.Region], probe.Status)
+	}
+
+	for region, s := range statuses {
+		health.Regions[region] = combineStatuses(s)
+		if health.Regions[region] != checkPass {
+			log.Warnf("Target %s of property %s is %s from %s", target, p.Property, health.Regions[region], region)
+		}
+	}
+
+	return health
+}
+
+// probeGTMTargetFrom runs curl and mtr to target from ghost location. API
+// failures are recorded in probe and only checks which ran decide its status,
+// err of probe is set when both failed.
+func probeGTMTargetFrom(location, region, target string, f flagSource) targetProbe {
+	probe := targetProbe{Location: location, Region: region}
+	ran, ok := 0, 0
+
+	curl, curlErr := runCurl(location, requestFromGhost, f)
+	if curlErr != nil {
+		probe.CurlError = errorText(newAPIError(curlErr))
+	} else {
+		probe.HTTPStatusCode = curl.HTTPStatusCode
+		ran++
+		if probe.HTTPStatusCode < 400 {
+			ok++
+		}
+	}
+
+	mtr, err := apiClient.ExecuteMtr(location, requestFromGhost, target, false)
+	if err != nil {
+		probe.MtrError = errorText(newAPIError(err))
+	} else {
+		mtrReport := newMtrReport(mtr)
+		probe.Reached = mtrReport.Findings.DestinationReached
+		probe.PacketLoss = mtrReport.PacketLoss
+		ran++
+		if probe.Reached {
+			ok++
+		}
+	}
+
+	switch {
+	case ran == 0:
+		probe.Status = checkErrored
+		probe.err = newAPIError(err)
+	case ok == 2:
+		probe.Status = checkPass
+	case ok == 0:
+		probe.Status = checkFail
+	default:
+		probe.Status = checkWarn
+	}
+
+	return probe
+}
+
+// combineStatuses passes when all statuses pass, fails when all fail and warns
+// otherwise. Errored statuses are left out, unless there are no others.
+func combineStatuses(statuses []string) string {
+	passed, failed, errored := 0, 0, 0
+	for _, s := range statuses {
+		switch s {
+		case checkPass:
+			passed++
+		case checkFail:
+			failed++
+		case checkErrored:
+			errored++
+		}
+	}
+
+	checked := len(statuses) - errored
+	switch {
+	case checked == 0:
+		return checkErrored
+	case passed == checked:
+		return checkPass
+	case failed == checked:
+		return checkFail
+	default:
+		return checkWarn
+	}
+}
+
+// withHostHeader adds Host header to headers, unless one is given already
+func withHostHeader(headers []string, host string) []string {
+	for _, header := range headers {
+		if strings.EqualFold(strings.TrimSpace(strings.SplitN(header, ":", 2)[0]), "host") {
+			return headers
+		}
+	}
+
+	return append([]string{"Host: " + host}, headers...)
+}
+
+// targetURL builds URL requesting path from target IP
+func targetURL(scheme, ip, path string) string {
+	host := ip
+	if strings.Contains(ip, ":") {
+		host = "[" + ip + "]"
+	}
+
+	return scheme + "://" + host + path
+}
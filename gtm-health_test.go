@@ -0,0 +1,142 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCombineStatuses(t *testing.T) {
+	tests := []struct {
+		statuses []string
+		want     string
+	}{
+		{[]string{checkPass, checkPass}, checkPass},
+		{[]string{checkFail, checkFail}, checkFail},
+		{[]string{checkPass, checkFail}, checkWarn},
+		{[]string{checkWarn}, checkWarn},
+		{[]string{checkPass, checkErrored}, checkPass},
+		{[]string{checkFail, checkErrored}, checkFail},
+		{[]string{checkErrored, checkErrored}, checkErrored},
+	}
+
+	for _, tt := range tests {
+		if got := combineStatuses(tt.statuses); got != tt.want {
+			t.Errorf("combineStatuses(%v) = %q, want %q", tt.statuses, got, tt.want)
+		}
+	}
+}
+
+func TestTargetURL(t *testing.T) {
+	tests := []struct {
+		scheme, ip, path string
+		want             string
+	}{
+		{"https", "192.0.2.20", "/", "https://192.0.2.20/"},
+		{"http", "2001:db8::1", "/health?full=1", "http://[2001:db8::1]/health?full=1"},
+	}
+
+	for _, tt := range tests {
+		if got := targetURL(tt.scheme, tt.ip, tt.path); got != tt.want {
+			t.Errorf("targetURL(%q, %q, %q) = %q, want %q", tt.scheme, tt.ip, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestGTMHealth(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name: "all properties",
+			args: []string{"gtm", "health", "--domain", "example.akadns.net", "--locations", "all"},
+			contains: []string{
+				`"regions": [
+        "AS",
+        "EU"
+    ]`,
+				`"property": "api"`,
+				`"testIps": [
+                "192.0.2.10"
+            ]`,
+				`"target": "192.0.2.20"`,
+				`"AS": "warn"`,
+				`"EU": "pass"`,
+			},
+		},
+		{
+			name:     "table",
+			args:     []string{"--output", "table", "gtm", "health", "--domain", "example.akadns.net", "--locations", "paris-france,tokyo-japan", "www"},
+			contains: []string{"PROPERTY", "TARGET", "AS", "EU", "192.0.2.21", "warn", "pass"},
+		},
+		{
+			name:     "failing target",
+			args:     []string{"gtm", "health", "--domain", fakeDownDomain, "--locations", "all", fakeDownProperty},
+			contains: []string{`"AS": "fail"`, `"EU": "pass"`, `"httpStatusCode": 504`},
+			exitCode: exitCheckFailed,
+		},
+		{
+			name:     "api errors",
+			args:     []string{"gtm", "health", "--domain", fakeDownDomain, "--locations", "all", fakeBrokenProperty},
+			contains: []string{`"AS": "error"`, `"EU": "error"`, `"curlError": "Internal Server Error`, `"mtrError": "Internal Server Error`},
+			exitCode: exitAPI,
+		},
+		{
+			name:     "api errors next to failing target",
+			args:     []string{"--output", "table", "gtm", "health", "--domain", fakeDownDomain, "--locations", "all"},
+			contains: []string{fakeDownIP, fakeBrokenIP, "fail", "error"},
+			exitCode: exitCheckFailed,
+		},
+		{
+			name:     "unknown property",
+			args:     []string{"gtm", "health", "--domain", "example.akadns.net", "ftp"},
+			exitCode: exitNotFound,
+		},
+		{
+			name:     "unknown domain",
+			args:     []string{"gtm", "health", "--domain", "other.akadns.net"},
+			exitCode: exitNotFound,
+		},
+		{
+			name:     "without domain",
+			args:     []string{"gtm", "health", "www"},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "invalid scheme",
+			args:     []string{"gtm", "health", "--domain", "example.akadns.net", "--scheme", "ftp"},
+			exitCode: exitInvalidFlag,
+		},
+	})
+}
+
+func TestGTMHealthHostHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"property host name", nil, "Host: www.example.akadns.net"},
+		{"given host", []string{"--header", "host: www.example.com"}, "host: www.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPI()
+			defer api.Close()
+
+			args := append([]string{"gtm", "health", "--domain", "example.akadns.net", "--locations", "paris-france"}, tt.args...)
+			if _, err := runCommand(t, api, append(args, "www")...); err != nil {
+				t.Fatal(err)
+			}
+
+			requests := api.curlRequests()
+			if len(requests) == 0 {
+				t.Fatal("no curl requests sent")
+			}
+
+			for _, r := range requests {
+				if len(r.RequestHeaders) == 0 || r.RequestHeaders[0] != tt.want || strings.Count(strings.ToLower(strings.Join(r.RequestHeaders, "\n")), "host:") != 1 {
+					t.Errorf("curl of %s sent headers %v, want single %q", r.URL, r.RequestHeaders, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -1,10 +1,7 @@
 package main
 
 import (
-	"os"
-
-	common "github.com/apiheat/akamai-cli-common"
-	log "github.com/sirupsen/logrus"
+	service "github.com/apiheat/go-edgegrid/v6/service/diagnosticv2"
 	"github.com/urfave/cli"
 )
 
@@ -17,25 +14,57 @@ func cmdListGTMIPAddresses(c *cli.Context) error {
 }
 
 func listGTM(c *cli.Context) error {
-	response, err := apiClient.ListGTMProperties()
-	common.ErrorCheck(err)
+	return runOnAccounts(c, func(client diagnosticClient) (interface{}, error) {
+		response, err := fetchGTMProperties(client)
+		if err != nil {
+			return nil, err
+		}
 
-	common.PrintJSON(outputJSON(response.GtmProperties))
-	return nil
+		return response.GtmProperties, nil
+	})
+}
+
+// fetchGTMProperties lists GTM properties. Their domains and names are
+// remembered for shell completion, unless they come from one of many accounts.
+func fetchGTMProperties(client diagnosticClient) (*service.GTMPropertiesResult, error) {
+	response, err := client.ListGTMProperties()
+	if err != nil {
+		return nil, newAPIError(err)
+	}
+
+	if len(accountClients) > 0 {
+		return response, nil
+	}
+
+	seen := map[string]bool{}
+	domains, properties := []string{}, []string{}
+	for _, p := range response.GtmProperties {
+		if !seen[p.Domain] {
+			seen[p.Domain] = true
+			domains = append(domains, p.Domain)
+		}
+		properties = append(properties, p.Property+"@"+p.Domain)
+	}
+
+	rememberCompletions(map[string][]string{completeGTMDomains: domains, completeGTMProperties: properties})
+
+	return response, nil
 }
 
 func listGTMIPs(c *cli.Context) error {
-	property := common.SetStringId(c, "Please provide PROPERTY. The Global Traffic Management property for which to collect IPs")
+	property, err := requireArgument(c, "Please provide PROPERTY. The Global Traffic Management property for which to collect IPs")
+	if err != nil {
+		return err
+	}
 
 	if c.String("domain") == "" {
-		log.Error("Provide domain, this is required parameter. The Global Traffic Management domain to which the property subdomain belongs")
-		os.Exit(4)
+		return newFlagError("Provide domain, this is required parameter. The Global Traffic Management domain to which the property subdomain belongs")
 	}
 
 	response, err := apiClient.ListGTMPropertyIPs(property, c.String("domain"))
-	common.ErrorCheck(err)
-
-	common.PrintJSON(outputJSON(response.GtmPropertyIps))
+	if err != nil {
+		return newAPIError(err)
+	}
 
-	return nil
+	return printOutput(c, response.GtmPropertyIps)
 }
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestGTMCommands(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name:     "properties",
+			args:     []string{"gtm", "properties"},
+			contains: []string{`"hostName": "www.example.akadns.net"`},
+		},
+		{
+			name:     "ip-addresses",
+			args:     []string{"gtm", "ip-addresses", "--domain", "example.akadns.net", "www"},
+			contains: []string{`"property": "www"`, `"192.0.2.20"`},
+		},
+		{
+			name:     "ip-addresses without domain",
+			args:     []string{"gtm", "ip-addresses", "www"},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "ip-addresses without property",
+			args:     []string{"gtm", "ip-addresses", "--domain", "example.akadns.net"},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "ip-addresses unknown property",
+			args:     []string{"gtm", "ip-addresses", "--domain", "example.akadns.net", fakeMissing},
+			exitCode: exitNotFound,
+		},
+	})
+}
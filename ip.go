@@ -1,13 +1,11 @@
 package main
 
 import (
-	"net/url"
-	"os"
-	"strconv"
+	"bytes"
+	"encoding/json"
+	"net"
 	"strings"
 
-	common "github.com/apiheat/akamai-cli-common"
-	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 )
 
@@ -32,147 +30,193 @@ func cmdIPCurl(c *cli.Context) error {
 }
 
 func ipCurl(c *cli.Context) error {
-	obj := common.SetStringId(c, "Please provide IP")
-
-	if !isIPv4(obj) {
-		log.Error("Provided IP address is not valid IPv4 address:", obj)
-		os.Exit(3)
-	}
+	return runOnTargets(c, "Please provide IP", func(target string, f flagSource) (interface{}, error) {
+		return runOnIP(target, func(ip string) (interface{}, error) {
+			if err := validateCurlOptions(f); err != nil {
+				return nil, err
+			}
 
-	if c.String("url") == "" {
-		log.Error("Provide url, this is required parameter")
-		os.Exit(4)
-	}
+			return runCurl(ip, requestFromIP, f)
+		})
+	})
+}
 
-	_, err := url.Parse(c.String("url"))
-	if err != nil {
-		log.Errorf("'url' is not valid URL: %s'", c.String("url"))
-		os.Exit(4)
-	}
+func ipMtr(c *cli.Context) error {
+	return runOnTargets(c, "Please provide IP", func(target string, f flagSource) (interface{}, error) {
+		return runOnIP(target, func(ip string) (interface{}, error) {
+			if err := validateMtrOptions(f); err != nil {
+				return nil, err
+			}
 
-	response, err := apiClient.ExecuteCurl(obj, requestFromIP, c.String("url"), c.String("user-agent"))
-	common.ErrorCheck(err)
+			response, err := apiClient.ExecuteMtr(ip, requestFromIP, f.String("destination-domain"), f.Bool("resolve-dns"))
+			if err != nil {
+				return nil, err
+			}
 
-	common.PrintJSON(outputJSON(response.CurlResults))
-	return nil
+			return mtrOutput(response), nil
+		})
+	})
 }
 
-func ipMtr(c *cli.Context) error {
-	obj := common.SetStringId(c, "Please provide IP")
+func ipDig(c *cli.Context) error {
+	return runOnTargets(c, "Please provide IP", func(target string, f flagSource) (interface{}, error) {
+		return runOnIP(target, func(ip string) (interface{}, error) {
+			if err := validateDigOptions(f); err != nil {
+				return nil, err
+			}
 
-	if !isIPv4(obj) {
-		log.Error("Provided IP address is not valid IPv4 address:", obj)
-		os.Exit(3)
-	}
+			response, err := apiClient.ExecuteDig(ip, requestFromIP, f.String("hostname"), f.String("query-type"))
+			if err != nil {
+				return nil, err
+			}
 
-	if c.String("destination-domain") == "" {
-		log.Error("Provide destination domain, this is required parameter")
-		os.Exit(4)
-	}
+			return digOutput(response, f), nil
+		})
+	})
+}
 
-	u, err := url.Parse(c.String("destination-domain"))
-	if err != nil {
-		log.Errorf("'destination-domain' is not valid URL: %s'", c.String("destination-domain"))
-		os.Exit(4)
-	}
+func ipGeolocation(c *cli.Context) error {
+	return runOnTargets(c, "Please provide IP", func(target string, f flagSource) (interface{}, error) {
+		return runOnIP(target, func(ip string) (interface{}, error) {
+			response, err := apiClient.RetrieveIPGeolocation(ip)
+			if err != nil {
+				return nil, err
+			}
+
+			return response.GeoLocation, nil
+		})
+	})
+}
 
-	if u.Scheme != "" {
-		log.Errorf("Please do not provide HTTP scheme in 'destination-domain' : %s'", c.String("destination-domain"))
-		os.Exit(4)
+func isCDNIP(c *cli.Context) error {
+	// Input is read once, as stdin cannot be read again for every account
+	targets, err := inputTargets(c)
+	if err != nil {
+		return err
 	}
 
-	response, err := apiClient.ExecuteMtr(obj, requestFromIP, c.String("destination-domain"), c.Bool("resolve-dns"))
-	common.ErrorCheck(err)
+	return runOnAccounts(c, func(client diagnosticClient) (interface{}, error) {
+		return collectTargets(c, targets, "Please provide IP", func(target string, f flagSource) (interface{}, error) {
+			return runOnIP(target, func(ip string) (interface{}, error) {
+				response, err := client.CheckIPAddress(ip)
+				if err != nil {
+					return nil, err
+				}
 
-	common.PrintJSON(outputJSON(response.Mtr))
-	return nil
+				return response, nil
+			})
+		})
+	})
 }
 
-func ipDig(c *cli.Context) error {
-	obj := common.SetStringId(c, "Please provide IP")
+const (
+	familyIPv4 = "IPv4"
+	familyIPv6 = "IPv6"
+)
 
-	allowedQueries := []string{"A", "AAAA", "CNAME", "MX", "NS", "PTR", "SOA"}
+// ipResult is result of ip command together with the address it was run
+// for and family of the address
+type ipResult struct {
+	IP     string      `json:"ip"`
+	Family string      `json:"family"`
+	Result interface{} `json:"result"`
+}
 
-	if !isIPv4(obj) {
-		log.Error("Provided IP address is not valid IPv4 address:", obj)
-		os.Exit(3)
-	}
+// MarshalJSON adds ip and family next to fields of result, so JSON shape of
+// ip commands stays as it was before address family was reported. Results
+// which are not objects, e.g. dig summary, are nested under result.
+func (r ipResult) MarshalJSON() ([]byte, error) {
+	type wrapped ipResult
 
-	if c.String("hostname") == "" {
-		log.Error("Provide hostname, this is required parameter")
-		os.Exit(4)
+	result, err := json.Marshal(r.Result)
+	if err != nil {
+		return nil, err
 	}
 
-	u, err := url.Parse(c.String("hostname"))
-	if err != nil {
-		log.Errorf("'hostname' is not valid URL: %s'", c.String("hostname"))
-		os.Exit(4)
+	result = bytes.TrimSpace(result)
+	if len(result) < 2 || result[0] != '{' {
+		return json.Marshal(wrapped(r))
 	}
 
-	if u.Scheme != "" {
-		log.Errorf("Please do not provide HTTP scheme in 'hostname' : %s'", c.String("hostname"))
-		os.Exit(4)
+	address, err := json.Marshal(struct {
+		IP     string `json:"ip"`
+		Family string `json:"family"`
+	}{r.IP, r.Family})
+	if err != nil {
+		return nil, err
 	}
 
-	if !common.IsStringInSlice(c.String("query-type"), allowedQueries) {
-		log.Error("Provided correct 'query-type': A, AAAA, CNAME, MX, NS, PTR, or SOA")
-		os.Exit(5)
+	fields := bytes.TrimSpace(result[1 : len(result)-1])
+	if len(fields) == 0 {
+		return address, nil
 	}
 
-	response, err := apiClient.ExecuteDig(obj, requestFromIP, c.String("hostname"), c.String("query-type"))
-	common.ErrorCheck(err)
+	return append(append(address[:len(address)-1], ','), result[1:]...), nil
+}
 
-	common.PrintJSON(outputJSON(response.DigInfo))
-	return nil
+func (r ipResult) tableHeader() []string {
+	header, _, _ := tableData(r.Result, false)
+	return append([]string{"ip", "family"}, header...)
 }
 
-func ipGeolocation(c *cli.Context) error {
-	ip := common.SetStringId(c, "Please provide IP")
+func (r ipResult) tableRows() [][]string {
+	_, rows, _ := tableData(r.Result, false)
 
-	if !isIPv4(ip) {
-		log.Info("Provided IP address is not valid IPv4 address:", ip)
-		os.Exit(3)
+	var prefixed [][]string
+	for _, row := range rows {
+		prefixed = append(prefixed, append([]string{r.IP, r.Family}, row...))
 	}
 
-	response, err := apiClient.RetrieveIPGeolocation(ip)
-	common.ErrorCheck(err)
-
-	common.PrintJSON(outputJSON(response.GeoLocation))
-	return nil
+	return prefixed
 }
 
-func isCDNIP(c *cli.Context) error {
-	ip := common.SetStringId(c, "Please provide IP")
+// runOnIP validates target address and runs fn with its canonical form,
+// result of fn is reported together with the address family
+func runOnIP(target string, fn func(ip string) (interface{}, error)) (interface{}, error) {
+	family, ip, ok := classifyIP(target)
+	if !ok {
+		return nil, newValidationError("Provided IP address is not valid IPv4 or IPv6 address: %s", target)
+	}
 
-	if !isIPv4(ip) {
-		log.Info("Provided IP address is not valid IPv4 address:", ip)
-		os.Exit(3)
+	result, err := fn(ip)
+	if err != nil {
+		return nil, err
 	}
 
-	response, err := apiClient.CheckIPAddress(ip)
-	common.ErrorCheck(err)
+	return ipResult{IP: ip, Family: family, Result: result}, nil
+}
 
-	common.PrintJSON(outputJSON(response))
+// classifyIP returns the address family of the given ip address and its
+// canonical form as expected by the API. Compressed and zoned ("%eth0")
+// IPv6 notations are accepted, the zone is dropped as it only has a meaning
+// on the local host.
+func classifyIP(host string) (string, string, bool) {
+	addr := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(host), "["), "]")
 
-	return nil
-}
+	if i := strings.LastIndex(addr, "%"); i != -1 && strings.Contains(addr, ":") {
+		addr = addr[:i]
+	}
 
-func isIPv4(host string) bool {
-	parts := strings.Split(host, ".")
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return "", "", false
+	}
 
-	if len(parts) < 4 {
-		return false
+	// IPv4-mapped IPv6 addresses are reported as plain IPv4 ones
+	if ip4 := ip.To4(); ip4 != nil {
+		return familyIPv4, ip4.String(), true
 	}
 
-	for _, x := range parts {
-		if i, err := strconv.Atoi(x); err == nil {
-			if i < 0 || i > 255 {
-				return false
-			}
-		} else {
-			return false
-		}
+	return familyIPv6, ip.String(), true
+}
 
+// validateIP fails when provided value is neither IPv4 nor IPv6 address,
+// otherwise it returns the address in the form which should be sent to API
+func validateIP(host string) (string, error) {
+	_, ip, ok := classifyIP(host)
+	if !ok {
+		return "", newValidationError("Provided IP address is not valid IPv4 or IPv6 address: %s", host)
 	}
-	return true
+
+	return ip, nil
 }
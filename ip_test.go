@@ -0,0 +1,125 @@
+package main
+
+import "testing"
+
+func TestClassifyIP(t *testing.T) {
+	tests := []struct {
+		host   string
+		family string
+		ip     string
+		ok     bool
+	}{
+		{"23.15.7.10", familyIPv4, "23.15.7.10", true},
+		{" 23.15.7.10 ", familyIPv4, "23.15.7.10", true},
+		{"2a02:26f0:d8:0:0:0:17d4:9d1", familyIPv6, "2a02:26f0:d8::17d4:9d1", true},
+		{"2a02:26f0:d8::17d4:9d1", familyIPv6, "2a02:26f0:d8::17d4:9d1", true},
+		{"[2a02:26f0:d8::17d4:9d1]", familyIPv6, "2a02:26f0:d8::17d4:9d1", true},
+		{"fe80::1%eth0", familyIPv6, "fe80::1", true},
+		{"::ffff:23.15.7.10", familyIPv4, "23.15.7.10", true},
+		{"::1", familyIPv6, "::1", true},
+		{"23.15.7", "", "", false},
+		{"256.1.1.1", "", "", false},
+		{"www.example.com", "", "", false},
+		{"1.2.3.4%eth0", "", "", false},
+		{"", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			family, ip, ok := classifyIP(tt.host)
+			if family != tt.family || ip != tt.ip || ok != tt.ok {
+				t.Errorf("classifyIP(%q) = %q, %q, %t, want %q, %q, %t", tt.host, family, ip, ok, tt.family, tt.ip, tt.ok)
+			}
+		})
+	}
+}
+
+func TestIPResultJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		result interface{}
+		want   string
+	}{
+		{"object", map[string]bool{"isCdnIp": true}, `{"ip":"23.15.7.10","family":"IPv4","isCdnIp":true}`},
+		{"empty object", struct{}{}, `{"ip":"23.15.7.10","family":"IPv4"}`},
+		{"list", []string{"www.example.com -> 23.15.7.10"}, `{"ip":"23.15.7.10","family":"IPv4","result":["www.example.com -> 23.15.7.10"]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := outputJSON(ipResult{IP: "23.15.7.10", Family: familyIPv4, Result: tt.result}); got != tt.want {
+				t.Errorf("outputJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPCommands(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name:     "is-cdn-ip ipv4",
+			args:     []string{"ip", "is-cdn-ip", "23.15.7.10"},
+			contains: []string{`"ip": "23.15.7.10"`, `"family": "IPv4"`, `"isCdnIp": true`},
+		},
+		{
+			name:     "is-cdn-ip ipv6",
+			args:     []string{"ip", "is-cdn-ip", "2a02:26f0:d8::17d4:9d1"},
+			contains: []string{`"ip": "2a02:26f0:d8::17d4:9d1"`, `"family": "IPv6"`, `"isCdnIp": true`},
+		},
+		{
+			name:     "is-cdn-ip missing argument",
+			args:     []string{"ip", "is-cdn-ip"},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "is-cdn-ip invalid address",
+			args:     []string{"ip", "is-cdn-ip", "23.15.7"},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "geolocation",
+			args:     []string{"ip", "geolocation", "23.15.7.10"},
+			contains: []string{`"clientIp": "23.15.7.10"`, `"countryCode": "DE"`},
+		},
+		{
+			name:     "dig",
+			args:     []string{"ip", "dig", "--hostname", "www.example.com", "23.15.7.10"},
+			contains: []string{`"hostname": "www.example.com"`, `"queryType": "A"`},
+		},
+		{
+			name:     "dig without hostname",
+			args:     []string{"ip", "dig", "23.15.7.10"},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "dig hostname with scheme",
+			args:     []string{"ip", "dig", "--hostname", "https://www.example.com", "23.15.7.10"},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "dig unsupported query type",
+			args:     []string{"ip", "dig", "--hostname", "www.example.com", "--query-type", "TXT", "23.15.7.10"},
+			exitCode: exitInvalidQueryType,
+		},
+		{
+			name:     "mtr",
+			args:     []string{"ip", "mtr", "--destination-domain", "www.example.com", "23.15.7.10"},
+			contains: []string{`"destination": "www.example.com"`},
+		},
+		{
+			name:     "mtr without destination",
+			args:     []string{"ip", "mtr", "23.15.7.10"},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "curl",
+			args:     []string{"ip", "curl", "--url", "https://www.example.com/", "23.15.7.10"},
+			contains: []string{`"httpStatusCode": 200`},
+		},
+		{
+			name:     "curl without url",
+			args:     []string{"ip", "curl", "23.15.7.10"},
+			exitCode: exitInvalidFlag,
+		},
+	})
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	stateDirEnvVar = "AKAMAI_DIAGNOSTIC_TOOLS_STATE_DIR"
+	stateDirName   = "akamai-cli-diagnostic-tools"
+	jobsFileName   = "translate-requests.json"
+
+	// finishedJobTTL is how long fetched and failed jobs are kept in job store
+	finishedJobTTL = 7 * 24 * time.Hour
+)
+
+// jobStoreMu serialises read-modify-write cycles of job store between goroutines
+var jobStoreMu sync.Mutex
+
+// Statuses of translate error requests kept in job store
+const (
+	jobStatusPending = "pending"
+	jobStatusReady   = "ready"
+	jobStatusFetched = "fetched"
+	jobStatusFailed  = "failed"
+)
+
+// translateJob is translate error request launched by this tool
+type translateJob struct {
+	RequestID   string    `json:"requestId"`
+	ErrorString string    `json:"errorString"`
+	LaunchedAt  time.Time `json:"launchedAt"`
+	RetryAfter  int       `json:"retryAfter"`
+	Status      string    `json:"status"`
+	CheckedAt   time.Time `json:"checkedAt"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// translateJobs is list of jobs presented as table with the most useful columns first
+type translateJobs []*translateJob
+
+func (jobs translateJobs) tableHeader() []string {
+	return []string{"requestId", "errorString", "status", "launchedAt", "checkedAt", "error"}
+}
+
+func (jobs translateJobs) tableRows() [][]string {
+	var rows [][]string
+	for _, job := range jobs {
+		rows = append(rows, []string{
+			job.RequestID,
+			job.ErrorString,
+			job.Status,
+			formatTime(job.LaunchedAt),
+			formatTime(job.CheckedAt),
+			job.Error,
+		})
+	}
+
+	return rows
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Local().Format(time.RFC3339)
+}
+
+// jobStore keeps launched translate error requests in local state file
+type jobStore struct {
+	path string
+	Jobs []*translateJob `json:"jobs"`
+}
+
+// stateDir returns directory where tool keeps its local state, it can be
+// overridden with AKAMAI_DIAGNOSTIC_TOOLS_STATE_DIR environment variable
+func stateDir() (string, error) {
+	if dir := os.Getenv(stateDirEnvVar); dir != "" {
+		return dir, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, stateDirName), nil
+}
+
+// stateFile returns path of given file in state directory creating the directory when needed
+func stateFile(name string) (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, name), nil
+}
+
+// writeStateFile atomically replaces state file with JSON representation of v.
+// It is written to unique temporary file in the same directory first, so
+// concurrent runs never write the same file and rename stays on one filesystem.
+func writeStateFile(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// readStateFile loads JSON state file into v, missing file is not an error
+func readStateFile(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+func loadJobStore() (*jobStore, error) {
+	path, err := stateFile(jobsFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &jobStore{path: path}
+	if err := readStateFile(path, store); err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Loaded %d translate error requests from %s", len(store.Jobs), path)
+
+	if pruned := store.prune(time.Now().Add(-finishedJobTTL)); pruned > 0 {
+		log.Debugf("Pruned %d translate error requests finished more than %s ago", pruned, finishedJobTTL)
+	}
+
+	return store, nil
+}
+
+func (s *jobStore) save() error {
+	return writeStateFile(s.path, s)
+}
+
+func (s *jobStore) add(job *translateJob) {
+	s.Jobs = append(s.Jobs, job)
+}
+
+func (s *jobStore) find(requestID string) *translateJob {
+	for _, job := range s.Jobs {
+		if job.RequestID == requestID {
+			return job
+		}
+	}
+
+	return nil
+}
+
+// prune removes fetched and failed jobs last checked before given time and
+// returns how many were removed. Pending and ready jobs are always kept.
+func (s *jobStore) prune(before time.Time) int {
+	var kept []*translateJob
+	for _, job := range s.Jobs {
+		finished := job.Status == jobStatusFetched || job.Status == jobStatusFailed
+		finishedAt := job.CheckedAt
+		if finishedAt.IsZero() {
+			finishedAt = job.LaunchedAt
+		}
+
+		if finished && finishedAt.Before(before) {
+			continue
+		}
+
+		kept = append(kept, job)
+	}
+
+	pruned := len(s.Jobs) - len(kept)
+	s.Jobs = kept
+
+	return pruned
+}
+
+// latest returns most recently launched job
+func (s *jobStore) latest() *translateJob {
+	var latest *translateJob
+	for _, job := range s.Jobs {
+		if latest == nil || job.LaunchedAt.After(latest.LaunchedAt) {
+			latest = job
+		}
+	}
+
+	return latest
+}
+
+// unfetched returns jobs which results were not retrieved yet, oldest first
+func (s *jobStore) unfetched() []*translateJob {
+	var jobs []*translateJob
+	for _, job := range s.Jobs {
+		if job.Status == jobStatusPending || job.Status == jobStatusReady {
+			jobs = append(jobs, job)
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].LaunchedAt.Before(jobs[j].LaunchedAt) })
+
+	return jobs
+}
+
+// updateJob records outcome of API call for job if it is known to the store
+func (s *jobStore) updateJob(requestID, status string, err error) {
+	job := s.find(requestID)
+	if job == nil {
+		return
+	}
+
+	job.Status = status
+	job.CheckedAt = time.Now()
+	job.Error = ""
+	if err != nil {
+		job.Error = errorText(err)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTranslateRequestJobStore(t *testing.T) {
+	dir, err := stateDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(dir + string(os.PathSeparator) + jobsFileName)
+
+	runCommandTests(t, []commandTest{
+		{
+			name:     "get latest without launched requests",
+			args:     []string{"translate-request", "get", "--latest"},
+			exitCode: exitNotFound,
+		},
+		{
+			name:     "launch first",
+			args:     []string{"translate-request", "launch", "#18.1"},
+			contains: []string{`"requestId": "req-18.1"`},
+		},
+		{
+			name:     "launch second",
+			args:     []string{"translate-request", "launch", "18.2"},
+			contains: []string{`"requestId": "req-18.2"`},
+		},
+		{
+			name:     "list pending",
+			args:     []string{"translate-request", "list", "--status", "pending"},
+			contains: []string{`"requestId": "req-18.1"`, `"errorString": "18.1"`, `"requestId": "req-18.2"`},
+		},
+		{
+			name:     "check updates status",
+			args:     []string{"translate-request", "check", "req-18.1"},
+			contains: []string{`"requestId": "req-18.1"`},
+		},
+		{
+			name:     "list ready",
+			args:     []string{"--output", "csv", "translate-request", "list", "--status", "ready"},
+			contains: []string{"req-18.1,18.1"},
+		},
+		{
+			name:     "get latest",
+			args:     []string{"translate-request", "get", "--latest"},
+			contains: []string{`"originHostname": "origin.example.com"`},
+		},
+		{
+			name:     "get all ready",
+			args:     []string{"--output", "ndjson", "translate-request", "get", "--all-ready"},
+			contains: []string{`{"requestId":"req-18.1","errorString":"18.1","result":{"translatedError":`},
+		},
+		{
+			name:     "list fetched",
+			args:     []string{"--output", "csv", "translate-request", "list", "--status", "fetched"},
+			contains: []string{"req-18.1,18.1", "req-18.2,18.2"},
+		},
+		{
+			name:     "get all ready when everything was fetched",
+			args:     []string{"translate-request", "get", "--all-ready"},
+			contains: []string{"[]"},
+		},
+	})
+}
+
+func TestJobStoreSelection(t *testing.T) {
+	store := &jobStore{}
+	if store.latest() != nil {
+		t.Fatal("latest() of empty store should be nil")
+	}
+
+	now := time.Now()
+	store.add(&translateJob{RequestID: "old", LaunchedAt: now.Add(-time.Hour), Status: jobStatusReady})
+	store.add(&translateJob{RequestID: "new", LaunchedAt: now, Status: jobStatusPending})
+	store.add(&translateJob{RequestID: "done", LaunchedAt: now.Add(-time.Minute), Status: jobStatusFetched})
+
+	if got := store.latest().RequestID; got != "new" {
+		t.Errorf("latest() = %s, want new", got)
+	}
+
+	unfetched := store.unfetched()
+	if len(unfetched) != 2 || unfetched[0].RequestID != "old" || unfetched[1].RequestID != "new" {
+		t.Errorf("unfetched() = %v", unfetched)
+	}
+
+	store.updateJob("old", jobStatusFetched, nil)
+	if job := store.find("old"); job.Status != jobStatusFetched || job.CheckedAt.IsZero() {
+		t.Errorf("updateJob() did not update job: %+v", job)
+	}
+}
+
+func TestJobStorePrune(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-2 * finishedJobTTL)
+
+	store := &jobStore{}
+	store.add(&translateJob{RequestID: "old fetched", LaunchedAt: old, CheckedAt: old, Status: jobStatusFetched})
+	store.add(&translateJob{RequestID: "old failed", LaunchedAt: old, Status: jobStatusFailed})
+	store.add(&translateJob{RequestID: "old pending", LaunchedAt: old, CheckedAt: old, Status: jobStatusPending})
+	store.add(&translateJob{RequestID: "old ready", LaunchedAt: old, CheckedAt: old, Status: jobStatusReady})
+	store.add(&translateJob{RequestID: "recently fetched", LaunchedAt: old, CheckedAt: now, Status: jobStatusFetched})
+
+	if pruned := store.prune(now.Add(-finishedJobTTL)); pruned != 2 {
+		t.Errorf("prune() = %d, want 2", pruned)
+	}
+
+	var kept []string
+	for _, job := range store.Jobs {
+		kept = append(kept, job.RequestID)
+	}
+
+	if fmt.Sprint(kept) != "[old pending old ready recently fetched]" {
+		t.Errorf("prune() kept %v", kept)
+	}
+}
+
+func TestWriteStateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, jobsFileName)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := writeStateFile(path, jobStore{Jobs: []*translateJob{{RequestID: fmt.Sprint(i)}}}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var store jobStore
+	if err := readStateFile(path, &store); err != nil || len(store.Jobs) != 1 {
+		t.Errorf("readStateFile() = %+v, %v, want single job", store, err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 1 {
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Name())
+		}
+		t.Errorf("state directory contains %v, want only %s", names, jobsFileName)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	locationsFileName = "ghost-locations.json"
+	locationsCacheTTL = 24 * time.Hour
+	maxSuggestions    = 3
+)
+
+// locationsCache keeps ghost locations in local state file, so that location
+// arguments can be validated and completed without calling API every time
+type locationsCache struct {
+	FetchedAt time.Time       `json:"fetchedAt"`
+	Locations []ghostLocation `json:"locations"`
+}
+
+func readLocationsCache() (*locationsCache, error) {
+	path, err := stateFile(locationsFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &locationsCache{}
+	if err := readStateFile(path, cache); err != nil {
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+func writeLocationsCache(locations []ghostLocation) {
+	path, err := stateFile(locationsFileName)
+	if err == nil {
+		err = writeStateFile(path, locationsCache{FetchedAt: time.Now(), Locations: locations})
+	}
+
+	if err != nil {
+		log.Warnf("Cannot save ghost locations cache: %s", err)
+	}
+}
+
+// knownGhostLocations returns ghost locations from local cache, or from API
+// when cache is older than a day or refresh is requested. It also tells
+// whether locations were just fetched from API.
+func knownGhostLocations(refresh bool) ([]ghostLocation, bool, error) {
+	if !refresh {
+		cache, err := readLocationsCache()
+		if err != nil {
+			log.Warnf("Cannot read ghost locations cache: %s", err)
+		} else if len(cache.Locations) > 0 && time.Since(cache.FetchedAt) < locationsCacheTTL {
+			return cache.Locations, false, nil
+		}
+	}
+
+	locations, err := fetchGhostLocations()
+	return locations, true, err
+}
+
+// validateGhostLocation checks that location ID exists and suggests the
+// closest ones when it does not. Locations are fetched again before giving
+// up, as cached list might miss recently added location. When locations
+// cannot be listed at all the check is skipped and API has the last word.
+func validateGhostLocation(id string) error {
+	locations, fetched, err := knownGhostLocations(false)
+	if err == nil && !hasGhostLocation(locations, id) && !fetched {
+		locations, _, err = knownGhostLocations(true)
+	}
+
+	if err != nil {
+		log.Warnf("Cannot validate ghost location '%s': %s", id, errorText(err))
+		return nil
+	}
+
+	if hasGhostLocation(locations, id) {
+		return nil
+	}
+
+	if suggestions := suggestGhostLocations(id, locations); len(suggestions) > 0 {
+		return newValidationError("Unknown ghost location '%s', did you mean: %s?", id, strings.Join(suggestions, ", "))
+	}
+
+	return newValidationError("Unknown ghost location '%s', run 'ghost locations' to list available ones", id)
+}
+
+func hasGhostLocation(locations []ghostLocation, id string) bool {
+	for _, l := range locations {
+		if l.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// suggestGhostLocations returns up to maxSuggestions location IDs closest to
+// given one by edit distance. Locations containing given text come first.
+func suggestGhostLocations(id string, locations []ghostLocation) []string {
+	type candidate struct {
+		id       string
+		distance int
+	}
+
+	id = strings.ToLower(strings.TrimSpace(id))
+
+	// Allow roughly one typo per three characters
+	limit := len(id)/3 + 1
+
+	var candidates []candidate
+	for _, l := range locations {
+		distance := editDistance(id, strings.ToLower(l.ID))
+		if id != "" && strings.Contains(strings.ToLower(l.ID), id) {
+			distance = 0
+		}
+
+		if distance <= limit {
+			candidates = append(candidates, candidate{l.ID, distance})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].id < candidates[j].id
+	})
+
+	var suggestions []string
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		suggestions = append(suggestions, candidates[i].id)
+	}
+
+	return suggestions
+}
+
+// editDistance is Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+// minInt returns the smallest of values, go.mod targets Go before the builtin min
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"paris", "", 5},
+		{"paris-france", "paris-france", 0},
+		{"paris-frnace", "paris-france", 2},
+		{"frankfurt-germny", "frankfurt-germany", 1},
+		{"tokyo", "kyoto", 4},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestGhostLocations(t *testing.T) {
+	locations := []ghostLocation{
+		newGhostLocation("frankfurt-germany", "Frankfurt, Germany"),
+		newGhostLocation("berlin-germany", "Berlin, Germany"),
+		newGhostLocation("paris-france", "Paris, France"),
+		newGhostLocation("tokyo-japan", "Tokyo, Japan"),
+	}
+
+	tests := []struct {
+		id   string
+		want []string
+	}{
+		{"frankfurt-germny", []string{"frankfurt-germany"}},
+		{"Paris-Frnace", []string{"paris-france"}},
+		{"germany", []string{"berlin-germany", "frankfurt-germany"}},
+		{"sydney-australia", nil},
+	}
+
+	for _, tt := range tests {
+		if got := suggestGhostLocations(tt.id, locations); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("suggestGhostLocations(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestUnknownGhostLocation(t *testing.T) {
+	api := newFakeAPI()
+	defer api.Close()
+
+	tests := []struct {
+		location string
+		message  string
+	}{
+		{"paris-frnace", "Unknown ghost location 'paris-frnace', did you mean: paris-france?"},
+		{"sydney-australia", "Unknown ghost location 'sydney-australia', run 'ghost locations' to list available ones"},
+	}
+
+	for _, tt := range tests {
+		_, err := runCommand(t, api, "ghost", "dig", "--hostname", "www.example.com", tt.location)
+		if exitCode(err) != exitValidation || err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("ghost dig %s error = %v, want %q", tt.location, err, tt.message)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	common "github.com/apiheat/akamai-cli-common"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// Sort orders of ghost locations
+var locationSortOrders = []string{"id", "name", "city", "country"}
+
+// ghostLocation is ghost location with city and country taken from its name,
+// e.g. 'New York, NY, United States'
+type ghostLocation struct {
+	ID          string `json:"id"`
+	Value       string `json:"value"`
+	City        string `json:"city"`
+	Country     string `json:"country"`
+	CountryCode string `json:"countryCode,omitempty"`
+	Continent   string `json:"continent,omitempty"`
+}
+
+// ghostLocations is list of locations presented as table with the most useful columns first
+type ghostLocations []ghostLocation
+
+func (locations ghostLocations) tableHeader() []string {
+	return []string{"id", "value", "city", "country", "countryCode", "continent"}
+}
+
+func (locations ghostLocations) tableRows() [][]string {
+	var rows [][]string
+	for _, l := range locations {
+		rows = append(rows, []string{l.ID, l.Value, l.City, l.Country, l.CountryCode, l.Continent})
+	}
+
+	return rows
+}
+
+// locationCount is number of ghost locations in country
+type locationCount struct {
+	Country     string `json:"country"`
+	CountryCode string `json:"countryCode,omitempty"`
+	Locations   int    `json:"locations"`
+}
+
+// nearestLocation is ghost location closest to IP address. Locations in the
+// same city are preferred, others are ranked by great-circle distance from
+// geolocated IP address. Only when IP address or locations have no known
+// coordinates, the closest one is the one in the same country or continent.
+type nearestLocation struct {
+	IP           string        `json:"ip"`
+	City         string        `json:"city"`
+	CountryCode  string        `json:"countryCode"`
+	Continent    string        `json:"continent"`
+	Match        string        `json:"match"`
+	DistanceKm   int           `json:"distanceKm,omitempty"`
+	Location     ghostLocation `json:"location"`
+	Alternatives []string      `json:"alternatives,omitempty"`
+}
+
+// nearestByDistance is how many locations ranked by distance are reported,
+// the nearest one and its alternatives
+const nearestByDistance = 5
+
+func newGhostLocation(id, value string) ghostLocation {
+	location := ghostLocation{ID: id, Value: value}
+
+	parts := strings.Split(value, ",")
+	location.City = strings.TrimSpace(parts[0])
+	location.Country = strings.TrimSpace(parts[len(parts)-1])
+
+	if c, ok := findCountry(location.Country); ok {
+		location.CountryCode = c.Code
+		location.Continent = c.Continent
+	}
+
+	return location
+}
+
+// fetchGhostLocations lists ghost locations available to run diagnostic tools
+// from API and refreshes local cache of them
+func fetchGhostLocations() ([]ghostLocation, error) {
+	response, err := apiClient.ListGhostLocations()
+	if err != nil {
+		return nil, newAPIError(err)
+	}
+
+	var locations []ghostLocation
+	for _, l := range response.Locations {
+		locations = append(locations, newGhostLocation(l.ID, l.Value))
+	}
+
+	writeLocationsCache(locations)
+
+	return locations, nil
+}
+
+// locationFilterFlags are flags of 'ghost locations' command
+func locationFilterFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "country",
+			Usage: "Show only locations in `COUNTRY`, either ISO code like DE or name like Germany",
+		},
+		cli.StringFlag{
+			Name:  "city",
+			Usage: "Show only locations in `CITY`",
+		},
+		cli.StringFlag{
+			Name:  "match",
+			Usage: "Show only locations which ID or name matches `REGEX`",
+		},
+		cli.StringFlag{
+			Name:  "sort",
+			Value: "id",
+			Usage: fmt.Sprintf("Sort locations by `FIELD`, one of: %s", strings.Join(locationSortOrders, ", ")),
+		},
+		cli.BoolFlag{
+			Name:  "summary",
+			Usage: "Show number of matching locations per country instead of locations",
+		},
+	}
+}
+
+func ghostListLocations(c *cli.Context) error {
+	if !common.IsStringInSlice(c.String("sort"), locationSortOrders) {
+		return newFlagError("Unsupported sort order '%s', use one of: %s", c.String("sort"), strings.Join(locationSortOrders, ", "))
+	}
+
+	var match *regexp.Regexp
+	if c.String("match") != "" {
+		var err error
+		if match, err = regexp.Compile(c.String("match")); err != nil {
+			return newFlagError("Invalid --match regular expression: %s", err)
+		}
+	}
+
+	all, err := fetchGhostLocations()
+	if err != nil {
+		return err
+	}
+
+	locations := filterGhostLocations(all, c.String("country"), c.String("city"), match)
+	sortGhostLocations(locations, c.String("sort"))
+
+	log.Infof("%d of %d ghost locations match", len(locations), len(all))
+
+	if c.Bool("summary") {
+		return printOutput(c, countLocations(locations))
+	}
+
+	return printOutput(c, locations)
+}
+
+func filterGhostLocations(locations []ghostLocation, countryFilter, city string, match *regexp.Regexp) ghostLocations {
+	filtered := ghostLocations{}
+
+	for _, l := range locations {
+		if countryFilter != "" && !strings.EqualFold(l.CountryCode, countryFilter) && !strings.EqualFold(l.Country, countryFilter) {
+			continue
+		}
+
+		if city != "" && !strings.EqualFold(l.City, city) {
+			continue
+		}
+
+		if match != nil && !match.MatchString(l.ID) && !match.MatchString(l.Value) {
+			continue
+		}
+
+		filtered = append(filtered, l)
+	}
+
+	return filtered
+}
+
+func sortGhostLocations(locations []ghostLocation, order string) {
+	key := func(l ghostLocation) string {
+		switch order {
+		case "name":
+			return strings.ToLower(l.Value)
+		case "city":
+			return strings.ToLower(l.City)
+		case "country":
+			return strings.ToLower(l.Country + ", " + l.City)
+		}
+
+		return l.ID
+	}
+
+	sort.SliceStable(locations, func(i, j int) bool { return key(locations[i]) < key(locations[j]) })
+}
+
+// countLocations returns number of locations per country, the biggest first
+func countLocations(locations []ghostLocation) []locationCount {
+	counts := map[string]*locationCount{}
+	for _, l := range locations {
+		if _, ok := counts[l.Country]; !ok {
+			counts[l.Country] = &locationCount{Country: l.Country, CountryCode: l.CountryCode}
+		}
+		counts[l.Country].Locations++
+	}
+
+	list := []locationCount{}
+	for _, count := range counts {
+		list = append(list, *count)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Locations != list[j].Locations {
+			return list[i].Locations > list[j].Locations
+		}
+		return list[i].Country < list[j].Country
+	})
+
+	return list
+}
+
+// nearFlag lets ghost commands run from location nearest to IP address
+func nearFlag() cli.Flag {
+	return cli.StringFlag{
+		Name:  "near",
+		Usage: "Run from ghost location nearest to `IP` address instead of GHOST_LOCATION argument",
+	}
+}
+
+func ghostNearestLocation(c *cli.Context) error {
+	ip := c.String("ip")
+	if ip == "" {
+		return newFlagError("Please provide --ip")
+	}
+
+	nearest, err := findNearestLocation(ip)
+	if err != nil {
+		return err
+	}
+
+	return printOutput(c, nearest)
+}
+
+// findNearestLocation geolocates IP address and picks ghost location closest to it
+func findNearestLocation(ip string) (nearestLocation, error) {
+	ip, err := validateIP(ip)
+	if err != nil {
+		return nearestLocation{}, err
+	}
+
+	geo, err := apiClient.RetrieveIPGeolocation(ip)
+	if err != nil {
+		return nearestLocation{}, newAPIError(err)
+	}
+
+	locations, _, err := knownGhostLocations(false)
+	if err != nil {
+		return nearestLocation{}, err
+	}
+
+	nearest := nearestLocation{
+		IP:          ip,
+		City:        geo.GeoLocation.City,
+		CountryCode: geo.GeoLocation.CountryCode,
+		Continent:   geo.GeoLocation.Continent,
+	}
+
+	var at *coordinates
+	if geo.GeoLocation.Latitude != 0 || geo.GeoLocation.Longitude != 0 {
+		at = &coordinates{Latitude: geo.GeoLocation.Latitude, Longitude: geo.GeoLocation.Longitude}
+	}
+
+	candidates, match := nearestCandidates(locations, nearest.City, nearest.CountryCode, nearest.Continent, at)
+	if len(candidates) == 0 {
+		return nearestLocation{}, notFoundError{err: fmt.Errorf("No ghost location found near %s ( %s, %s, %s )", ip, nearest.City, nearest.CountryCode, nearest.Continent)}
+	}
+
+	nearest.Match = match
+	nearest.Location = candidates[0]
+	for _, l := range candidates[1:] {
+		nearest.Alternatives = append(nearest.Alternatives, l.ID)
+	}
+
+	if to, ok := findCityCoordinates(nearest.Location.CountryCode, nearest.Location.City); ok && at != nil {
+		nearest.DistanceKm = int(math.Round(distanceKm(*at, to)))
+	}
+
+	return nearest, nil
+}
+
+// nearestCandidates returns ghost locations in the same city. When there are
+// none, locations with known coordinates are ranked by distance from at, and
+// only when there are no coordinates, locations in the same country or
+// continent are returned. Level matched is returned too.
+func nearestCandidates(locations []ghostLocation, city, countryCode, continent string, at *coordinates) ([]ghostLocation, string) {
+	levels := []struct {
+		name    string
+		matches func(l ghostLocation) bool
+	}{
+		{"city", func(l ghostLocation) bool {
+			return city != "" && strings.EqualFold(l.City, city) && strings.EqualFold(l.CountryCode, countryCode)
+		}},
+		{"country", func(l ghostLocation) bool { return countryCode != "" && strings.EqualFold(l.CountryCode, countryCode) }},
+		{"continent", func(l ghostLocation) bool { return continent != "" && strings.EqualFold(l.Continent, continent) }},
+	}
+
+	for i, level := range levels {
+		if i == 1 && at != nil {
+			if candidates := nearestByCoordinates(locations, *at); len(candidates) > 0 {
+				return candidates, "distance"
+			}
+		}
+
+		var candidates []ghostLocation
+		for _, l := range locations {
+			if level.matches(l) {
+				candidates = append(candidates, l)
+			}
+		}
+
+		if len(candidates) > 0 {
+			sortGhostLocations(candidates, "id")
+			return candidates, level.name
+		}
+	}
+
+	return nil, ""
+}
+
+// nearestByCoordinates returns locations with known coordinates closest to at,
+// nearest first
+func nearestByCoordinates(locations []ghostLocation, at coordinates) []ghostLocation {
+	type ranked struct {
+		location ghostLocation
+		distance float64
+	}
+
+	var list []ranked
+	for _, l := range locations {
+		if c, ok := findCityCoordinates(l.CountryCode, l.City); ok {
+			list = append(list, ranked{l, distanceKm(at, c)})
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].distance != list[j].distance {
+			return list[i].distance < list[j].distance
+		}
+		return list[i].location.ID < list[j].location.ID
+	})
+
+	var candidates []ghostLocation
+	for i := 0; i < len(list) && i < nearestByDistance; i++ {
+		candidates = append(candidates, list[i].location)
+	}
+
+	return candidates
+}
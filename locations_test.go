@@ -0,0 +1,152 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewGhostLocation(t *testing.T) {
+	got := newGhostLocation("newyork-ny-unitedstates", "New York, NY, United States")
+	want := ghostLocation{
+		ID:          "newyork-ny-unitedstates",
+		Value:       "New York, NY, United States",
+		City:        "New York",
+		Country:     "United States",
+		CountryCode: "US",
+		Continent:   "NA",
+	}
+
+	if got != want {
+		t.Errorf("newGhostLocation() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNearestCandidates(t *testing.T) {
+	locations := []ghostLocation{
+		newGhostLocation("paris-france", "Paris, France"),
+		newGhostLocation("frankfurt-germany", "Frankfurt, Germany"),
+		newGhostLocation("berlin-germany", "Berlin, Germany"),
+		newGhostLocation("tokyo-japan", "Tokyo, Japan"),
+	}
+
+	tests := []struct {
+		name                     string
+		city, country, continent string
+		at                       *coordinates
+		want                     []string
+		match                    string
+	}{
+		{"city", "FRANKFURT", "DE", "EU", &coordinates{50.12, 8.68}, []string{"frankfurt-germany"}, "city"},
+		{"distance", "Munich", "DE", "EU", &coordinates{48.14, 11.58}, []string{"frankfurt-germany", "berlin-germany", "paris-france", "tokyo-japan"}, "distance"},
+		{"distance across border", "Strasbourg", "FR", "EU", &coordinates{48.58, 7.75}, []string{"frankfurt-germany", "paris-france", "berlin-germany", "tokyo-japan"}, "distance"},
+		{"distance across continents", "Seoul", "KR", "AS", &coordinates{37.57, 126.98}, []string{"tokyo-japan", "berlin-germany", "frankfurt-germany", "paris-france"}, "distance"},
+		{"country", "Munich", "DE", "EU", nil, []string{"berlin-germany", "frankfurt-germany"}, "country"},
+		{"continent", "Madrid", "ES", "EU", nil, []string{"berlin-germany", "frankfurt-germany", "paris-france"}, "continent"},
+		{"nothing", "Lima", "PE", "SA", nil, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates, match := nearestCandidates(locations, tt.city, tt.country, tt.continent, tt.at)
+
+			var ids []string
+			for _, l := range candidates {
+				ids = append(ids, l.ID)
+			}
+
+			if !reflect.DeepEqual(ids, tt.want) || match != tt.match {
+				t.Errorf("nearestCandidates() = %v by %q, want %v by %q", ids, match, tt.want, tt.match)
+			}
+		})
+	}
+}
+
+func TestNearestCandidatesWithoutCoordinates(t *testing.T) {
+	locations := []ghostLocation{
+		newGhostLocation("paris-france", "Paris, France"),
+		newGhostLocation("smallville-germany", "Smallville, Germany"),
+	}
+
+	candidates, match := nearestCandidates(locations, "Munich", "DE", "EU", &coordinates{48.14, 11.58})
+	if len(candidates) != 1 || candidates[0].ID != "paris-france" || match != "distance" {
+		t.Errorf("nearestCandidates() = %v by %q, want paris-france by distance", candidates, match)
+	}
+
+	candidates, match = nearestCandidates(locations[1:], "Munich", "DE", "EU", &coordinates{48.14, 11.58})
+	if len(candidates) != 1 || candidates[0].ID != "smallville-germany" || match != "country" {
+		t.Errorf("nearestCandidates() = %v by %q, want smallville-germany by country", candidates, match)
+	}
+}
+
+func TestDistanceKm(t *testing.T) {
+	paris, _ := findCityCoordinates("FR", "Paris")
+	frankfurt, _ := findCityCoordinates("de", "FRANKFURT")
+
+	if got := distanceKm(paris, frankfurt); got < 470 || got > 490 {
+		t.Errorf("distanceKm(Paris, Frankfurt) = %.0f, want about 480", got)
+	}
+
+	if got := distanceKm(paris, paris); got != 0 {
+		t.Errorf("distanceKm(Paris, Paris) = %f, want 0", got)
+	}
+}
+
+func TestGhostLocations(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name:     "country code",
+			args:     []string{"--output", "csv", "ghost", "locations", "--country", "de"},
+			contains: []string{"id,value,city,country,countryCode,continent\nberlin-germany,", "\nfrankfurt-germany,\"Frankfurt, Germany\",Frankfurt,Germany,DE,EU\n"},
+		},
+		{
+			name:     "city",
+			args:     []string{"ghost", "locations", "--city", "paris"},
+			contains: []string{`"id": "paris-france"`},
+		},
+		{
+			name:     "match and sort",
+			args:     []string{"--output", "csv", "ghost", "locations", "--match", "^(tokyo|paris)", "--sort", "country"},
+			contains: []string{"\nparis-france,\"Paris, France\",Paris,France,FR,EU\ntokyo-japan,"},
+		},
+		{
+			name:     "summary",
+			args:     []string{"--output", "csv", "ghost", "locations", "--summary"},
+			contains: []string{"country,countryCode,locations\nGermany,DE,2\nFrance,FR,1\nJapan,JP,1\n"},
+		},
+		{
+			name:     "invalid regex",
+			args:     []string{"ghost", "locations", "--match", "("},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "invalid sort",
+			args:     []string{"ghost", "locations", "--sort", "size"},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "nearest",
+			args:     []string{"ghost", "locations", "nearest", "--ip", "198.51.100.7"},
+			contains: []string{`"match": "city"`, `"distanceKm": 1`, `"id": "frankfurt-germany"`},
+		},
+		{
+			name:     "nearest without ip",
+			args:     []string{"ghost", "locations", "nearest"},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "dig near ip",
+			args:     []string{"ghost", "dig", "--hostname", "www.example.com", "--near", "198.51.100.7"},
+			contains: []string{`"hostname": "www.example.com"`},
+		},
+		{
+			name:     "mtr near ip runs from nearest location",
+			args:     []string{"ghost", "mtr", "--destination-domain", "www.example.com", "--near", "198.51.100.7"},
+			contains: []string{`"source": "frankfurt-germany"`},
+		},
+		{
+			name:     "near with location",
+			args:     []string{"ghost", "curl", "--url", "https://www.example.com/", "--near", "198.51.100.7", "paris-france"},
+			exitCode: exitValidation,
+		},
+	})
+}
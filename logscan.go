@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	service "github.com/apiheat/go-edgegrid/v6/service/diagnosticv2"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// referencePattern matches Akamai error reference strings, e.g.
+// 'Reference #18.6f64d440.1318965461.2f2b078' as shown on edge error pages
+var referencePattern = regexp.MustCompile(`\b\d{1,3}\.[0-9a-f]{6,8}\.\d{10}\.[0-9a-f]{1,8}\b`)
+
+// errorGroup aggregates translated errors sharing the same value of a field
+type errorGroup struct {
+	Value       string   `json:"value"`
+	Occurrences int      `json:"occurrences"`
+	References  []string `json:"references"`
+}
+
+// logErrorReport is consolidated outcome of translating all references found in log
+type logErrorReport struct {
+	Occurrences  int                  `json:"occurrences"`
+	Unique       int                  `json:"unique"`
+	Translated   int                  `json:"translated"`
+	ByReason     []errorGroup         `json:"byReason"`
+	ByEdgeServer []errorGroup         `json:"byEdgeServer"`
+	ByOrigin     []errorGroup         `json:"byOrigin"`
+	Failed       []translateJobResult `json:"failed,omitempty"`
+	errs         []error
+}
+
+func (r logErrorReport) tableHeader() []string {
+	return []string{"group", "value", "occurrences", "references"}
+}
+
+func (r logErrorReport) tableRows() [][]string {
+	var rows [][]string
+
+	for _, section := range []struct {
+		name   string
+		groups []errorGroup
+	}{
+		{"reason", r.ByReason},
+		{"edge server", r.ByEdgeServer},
+		{"origin", r.ByOrigin},
+	} {
+		for _, g := range section.groups {
+			rows = append(rows, []string{section.name, g.Value, strconv.Itoa(g.Occurrences), strings.Join(g.References, " ")})
+		}
+	}
+
+	for _, f := range r.Failed {
+		rows = append(rows, []string{"failed", f.Error, "", f.ErrorString})
+	}
+
+	return rows
+}
+
+// failure returns the most severe error when no reference could be translated
+func (r logErrorReport) failure() error {
+	return allFailed(r.errs, r.Unique)
+}
+
+// scanReferences finds Akamai reference strings in access logs or pasted
+// customer reports and returns how many times each of them occurred
+func scanReferences(r io.Reader) (map[string]int, []string, error) {
+	counts := map[string]int{}
+	var order []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		// Error pages are often pasted as HTML with '#' and '.' escaped
+		line := html.UnescapeString(scanner.Text())
+
+		for _, ref := range referencePattern.FindAllString(line, -1) {
+			if counts[ref] == 0 {
+				order = append(order, ref)
+			}
+			counts[ref]++
+		}
+	}
+
+	return counts, order, scanner.Err()
+}
+
+// translateFromLog translates every unique reference string found in log concurrently
+func translateFromLog(c *cli.Context) error {
+	var r io.Reader = os.Stdin
+	if name := c.String("from-log"); name != stdinInput {
+		f, err := os.Open(name)
+		if err != nil {
+			return newValidationError("Cannot read log: %s", err)
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	counts, refs, err := scanReferences(r)
+	if err != nil {
+		return newValidationError("Cannot read log: %s", err)
+	}
+
+	if len(refs) == 0 {
+		return newValidationError("No Akamai reference strings found in '%s'", c.String("from-log"))
+	}
+
+	log.Infof("Found %d unique reference strings, translating them", len(refs))
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	var targets []batchTarget
+	for _, ref := range refs {
+		targets = append(targets, batchTarget{Target: ref})
+	}
+
+	records := runBatch(c, targets, func(ref string, f flagSource) (interface{}, error) {
+		return translateCached(ctx, ref, f, false)
+	})
+
+	report := buildLogErrorReport(counts, records)
+	if err := printOutput(c, report); err != nil {
+		return err
+	}
+
+	return report.failure()
+}
+
+func buildLogErrorReport(counts map[string]int, records []batchRecord) logErrorReport {
+	report := logErrorReport{Unique: len(records)}
+
+	byReason := map[string]*errorGroup{}
+	byEdgeServer := map[string]*errorGroup{}
+	byOrigin := map[string]*errorGroup{}
+
+	for _, record := range records {
+		report.Occurrences += counts[record.Target]
+
+		translated, ok := record.Result.(*service.TranslatedError)
+		if record.Error != "" || !ok || translated == nil {
+			report.Failed = append(report.Failed, translateJobResult{ErrorString: record.Target, Error: record.Error})
+			if record.err != nil {
+				report.errs = append(report.errs, record.err)
+			}
+			continue
+		}
+
+		report.Translated++
+
+		te := translated.TranslatedError
+		origin := te.OriginHostname
+		if origin == "" {
+			origin = te.OriginIP
+		}
+
+		addToGroup(byReason, te.ReasonForFailure, record.Target, counts[record.Target])
+		addToGroup(byEdgeServer, te.ServerIP, record.Target, counts[record.Target])
+		addToGroup(byOrigin, origin, record.Target, counts[record.Target])
+	}
+
+	report.ByReason = sortedGroups(byReason)
+	report.ByEdgeServer = sortedGroups(byEdgeServer)
+	report.ByOrigin = sortedGroups(byOrigin)
+
+	return report
+}
+
+func addToGroup(groups map[string]*errorGroup, value, ref string, occurrences int) {
+	if value == "" {
+		value = "unknown"
+	}
+
+	g, ok := groups[value]
+	if !ok {
+		g = &errorGroup{Value: value}
+		groups[value] = g
+	}
+
+	g.Occurrences += occurrences
+	g.References = append(g.References, ref)
+}
+
+// sortedGroups returns groups with the most frequent first
+func sortedGroups(groups map[string]*errorGroup) []errorGroup {
+	list := []errorGroup{}
+	for _, g := range groups {
+		list = append(list, *g)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Occurrences != list[j].Occurrences {
+			return list[i].Occurrences > list[j].Occurrences
+		}
+		return list[i].Value < list[j].Value
+	})
+
+	return list
+}
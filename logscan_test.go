@@ -0,0 +1,92 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanReferences(t *testing.T) {
+	input := `10.0.0.1 - - "GET / HTTP/1.1" 503 "Reference #18.6f64d440.1318965461.2f2b078"
+<p>Reference&#32;&#35;18&#46;6f64d440&#46;1318965461&#46;2f2b078</p>
+ticket: customers see 97.1a2b3c4d.1700000000.abc12 and #18.6f64d440.1318965461.2f2b078
+no reference here 1.2.3.4
+`
+
+	counts, order, err := scanReferences(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantOrder := []string{"18.6f64d440.1318965461.2f2b078", "97.1a2b3c4d.1700000000.abc12"}
+	if !reflect.DeepEqual(order, wantOrder) {
+		t.Errorf("order = %v, want %v", order, wantOrder)
+	}
+
+	wantCounts := map[string]int{"18.6f64d440.1318965461.2f2b078": 3, "97.1a2b3c4d.1700000000.abc12": 1}
+	if !reflect.DeepEqual(counts, wantCounts) {
+		t.Errorf("counts = %v, want %v", counts, wantCounts)
+	}
+}
+
+func TestTranslateFromLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logscan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	access := filepath.Join(dir, "access.log")
+	ioutil.WriteFile(access, []byte(strings.Repeat("503 Reference #18.6f64d440.1318965461.2f2b078\n", 3)+"503 Reference #9.1a2b3c4d.1700000000.abc12\n"), 0600)
+
+	broken := filepath.Join(dir, "broken.log")
+	ioutil.WriteFile(broken, []byte("503 Reference #"+fakeBrokenReference+"\n503 Reference #"+fakeBrokenReference+"\n"), 0600)
+
+	mixed := filepath.Join(dir, "mixed.log")
+	ioutil.WriteFile(mixed, []byte("503 Reference #"+fakeBrokenReference+"\n503 Reference #9.1a2b3c4d.1700000000.abc12\n"), 0600)
+
+	clean := filepath.Join(dir, "clean.log")
+	ioutil.WriteFile(clean, []byte("200 OK\n"), 0600)
+
+	runCommandTests(t, []commandTest{
+		{
+			name:     "grouped report",
+			args:     []string{"translate-error", "--from-log", access},
+			contains: []string{`"occurrences": 4`, `"unique": 2`, `"translated": 2`, `"value": "Connection to origin server timed out"`, `"value": "23.15.7.10"`, `"value": "origin.example.com"`},
+		},
+		{
+			name:     "table",
+			args:     []string{"--output", "table", "translate-error", "--from-log", access},
+			contains: []string{"GROUP", "edge server", "23.15.7.10", "4"},
+		},
+		{
+			name:     "every translation failed",
+			args:     []string{"translate-error", "--from-log", broken},
+			contains: []string{`"occurrences": 2`, `"translated": 0`, `"error": "Internal Server Error`},
+			exitCode: exitAPI,
+		},
+		{
+			name:     "some translations failed",
+			args:     []string{"translate-error", "--from-log", mixed},
+			contains: []string{`"translated": 1`, `"errorString": "` + fakeBrokenReference + `"`},
+		},
+		{
+			name:     "no references",
+			args:     []string{"translate-error", "--from-log", clean},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "missing log",
+			args:     []string{"translate-error", "--from-log", filepath.Join(dir, "missing.log")},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "with input",
+			args:     []string{"translate-error", "--from-log", access, "--input", access},
+			exitCode: exitValidation,
+		},
+	})
+}
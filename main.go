@@ -4,17 +4,18 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strings"
+	"time"
 
 	common "github.com/apiheat/akamai-cli-common"
 	edgegrid "github.com/apiheat/go-edgegrid/v6/edgegrid"
-	service "github.com/apiheat/go-edgegrid/v6/service/diagnosticv2"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/urfave/cli"
 )
 
 var (
-	apiClient       *service.Diagnosticv2
+	apiClient       diagnosticClient
 	appName, appVer string
 )
 
@@ -24,8 +25,28 @@ const (
 )
 
 func main() {
+	err := newApp().Run(os.Args)
+	if err != nil {
+		log.Error(errorText(err))
+	}
+
+	os.Exit(exitCode(err))
+}
+
+// newApp builds command line application with all commands and flags
+func newApp() *cli.App {
 	app := common.CreateNewApp(appName, "A CLI to interact with Akamai Diagnostic Tools", appVer)
-	app.Flags = common.CreateFlags()
+	app.Flags = append(accountFlags(common.CreateFlags()),
+		cli.StringFlag{
+			Name:   "output, o",
+			Value:  outputFormatJSON,
+			Usage:  fmt.Sprintf("Output `FORMAT` of command results, one of: %s", strings.Join(outputFormats, ", ")),
+			EnvVar: "AKAMAI_DIAGNOSTIC_TOOLS_OUTPUT",
+		},
+		credentialHelperFlag(),
+	)
+
+	app.EnableBashCompletion = true
 
 	app.Commands = []cli.Command{
 		{
@@ -36,34 +57,119 @@ func main() {
 				{
 					Name:      "launch",
 					Usage:     "Launches a request to retrieve the data about error asynchronously. Check the poll link after the retryAfter interval or use the requestID to Check an Error Translation Request",
-					UsageText: fmt.Sprintf("%s translate-error launch [command options] ERROR_CODE", appName),
+					UsageText: fmt.Sprintf("%s translate-request launch [command options] ERROR_CODE", appName),
 					Action:    cmdLaunchTranslateErrorRequest,
 				},
 				{
-					Name:      "check",
-					Usage:     "After running 'launch' command this checks the status of an asynchronous request for data. A 200 PollResponse with a Retry-After header indicates the request is still processing. When the data is ready, a 303 response provides a Location header where you can GET the data using the 'get' command",
-					UsageText: fmt.Sprintf("%s translate-error check [command options] REQUEST_ID_FROM_LAUNCH_OUTPUT", appName),
-					Action:    cmdCheckTranslateErrorRequest,
+					Name:         "check",
+					Usage:        "After running 'launch' command this checks the status of an asynchronous request for data. A 200 PollResponse with a Retry-After header indicates the request is still processing. When the data is ready, a 303 response provides a Location header where you can GET the data using the 'get' command",
+					UsageText:    fmt.Sprintf("%s translate-request check [command options] REQUEST_ID_FROM_LAUNCH_OUTPUT", appName),
+					Action:       cmdCheckTranslateErrorRequest,
+					BashComplete: completeWith(unfetchedRequestIDs, nil),
+				},
+				{
+					Name:         "get",
+					Usage:        "Get information about error strings produced by edge servers when a request to retrieve content fails. The error represents an instance of a problem, and this operation gets details on what happened",
+					UsageText:    fmt.Sprintf("%s translate-request get [command options] REQUEST_ID_FROM_LAUNCH_OUTPUT|--latest|--all-ready", appName),
+					Action:       cmdGetTranslateErrorRequest,
+					BashComplete: completeWith(unfetchedRequestIDs, nil),
+					Flags: append(cacheFlags(),
+						cli.BoolFlag{
+							Name:  "latest",
+							Usage: "Get the most recently launched request instead of REQUEST_ID",
+						},
+						cli.BoolFlag{
+							Name:  "all-ready",
+							Usage: "Get all launched requests which finished processing and were not retrieved yet",
+						},
+					),
 				},
 				{
-					Name:      "get",
-					Usage:     "Get information about error strings produced by edge servers when a request to retrieve content fails. The error represents an instance of a problem, and this operation gets details on what happened",
-					UsageText: fmt.Sprintf("%s translate-error check [command options] REQUEST_ID_FROM_LAUNCH_OUTPUT", appName),
-					Action:    cmdGetTranslateErrorRequest,
+					Name:         "wait",
+					Usage:        "Wait until translate error requests finish processing and print translated errors, with ndjson output every one as soon as it is ready. Without REQUEST_ID waits for all pending requests launched from this machine",
+					UsageText:    fmt.Sprintf("%s translate-request wait [command options] [REQUEST_ID...]", appName),
+					Action:       cmdWaitTranslateErrorRequests,
+					BashComplete: completeWith(unfetchedRequestIDs, nil),
+					Flags: []cli.Flag{
+						cli.DurationFlag{
+							Name:  "timeout",
+							Value: 5 * time.Minute,
+							Usage: "Give up waiting after `DURATION`, e.g. 90s or 10m",
+						},
+					},
+				},
+				{
+					Name:      "list",
+					Usage:     "List translate error requests launched from this machine together with their last known status",
+					UsageText: fmt.Sprintf("%s translate-request list [command options]", appName),
+					Action:    cmdListTranslateErrorRequests,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "status",
+							Value: "",
+							Usage: "Show only requests with given `STATUS`: pending, ready, fetched or failed",
+						},
+					},
 				},
 			},
 		},
 		{
 			Name:      "translate-error",
 			Aliases:   []string{"t"},
-			UsageText: fmt.Sprintf("%s translate-error 'Error String' --retries N", appName),
+			UsageText: fmt.Sprintf("%s translate-error 'Error String'|--input FILE|--from-log FILE --timeout DURATION", appName),
 			Usage:     "Get information about error strings produced by edge servers when a request to retrieve content fails",
 			Action:    cmdTranslateError,
-			Flags: []cli.Flag{
+			Flags: append(append(batchFlags(), cacheFlags()...),
+				workersFlag(),
+				cli.StringFlag{
+					Name:  "from-log",
+					Usage: "Translate every Akamai reference string found in log `FILE` ('-' for stdin) and report them grouped by reason, edge server and origin",
+				},
+				cli.DurationFlag{
+					Name:  "timeout",
+					Value: 5 * time.Minute,
+					Usage: "Give up waiting for translation after `DURATION`, e.g. 90s or 2m. Request can be resumed later with 'translate-request get'",
+				},
 				cli.IntFlag{
 					Name:  "retries",
 					Value: 50,
-					Usage: "`Number` of retries to get translation request result",
+					Usage: "Maximum `Number` of polls of translation request result",
+				},
+				cli.BoolFlag{
+					Name:  "no-progress",
+					Usage: "Do not report progress on stderr",
+				},
+			),
+		},
+		{
+			Name:  "cache",
+			Usage: "Manage local cache of translated error strings",
+			Subcommands: []cli.Command{
+				{
+					Name:      "stats",
+					Usage:     "Show number of cached translations, how many of them are still fresh and size of the cache",
+					UsageText: fmt.Sprintf("%s cache stats [command options]", appName),
+					Action:    cmdCacheStats,
+					Flags:     []cli.Flag{cacheTTLFlag()},
+				},
+				{
+					Name:      "purge",
+					Usage:     "Remove cached translations",
+					UsageText: fmt.Sprintf("%s cache purge [command options]", appName),
+					Action:    cmdCachePurge,
+					Flags: []cli.Flag{
+						cacheTTLFlag(),
+						cli.BoolFlag{
+							Name:  "expired",
+							Usage: "Remove only translations older than --cache-ttl",
+						},
+					},
+				},
+				{
+					Name:      "export",
+					Usage:     "Print all cached translations, oldest first",
+					UsageText: fmt.Sprintf("%s cache export", appName),
+					Action:    cmdCacheExport,
 				},
 			},
 		},
@@ -78,10 +184,35 @@ func main() {
 					Action:    cmdListGTMProperties,
 				},
 				{
-					Name:      "ip-addresses",
-					Usage:     "Gets test and target IPs for a domain and property. Run List GTM Properties for domain and property parameter values. PROPERTY - The Global Traffic Management property for which to collect IPs",
-					UsageText: fmt.Sprintf("%s gtm ip-addresses --domain DOMAIN PROPERTY", appName),
-					Action:    cmdListGTMIPAddresses,
+					Name:         "health",
+					Usage:        "Run curl and mtr from many locations against datacenter targets of Global Traffic Management properties and show status of every target per region. Without PROPERTY all properties of domain are checked",
+					UsageText:    fmt.Sprintf("%s gtm health [command options] --domain DOMAIN [PROPERTY...]", appName),
+					Action:       cmdGTMHealth,
+					BashComplete: completeWith(gtmPropertyNames, map[string]func() []string{"domain": gtmDomains, "locations": ghostLocationIDs}),
+					Flags: append(append(ghostLocationFlags(), curlRequestFlags()...),
+						cli.StringFlag{
+							Name:  "domain",
+							Value: "",
+							Usage: "The Global Traffic Management domain which properties to check",
+						},
+						cli.StringFlag{
+							Name:  "scheme",
+							Value: "https",
+							Usage: "Scheme of URL requested from targets, either http or https",
+						},
+						cli.StringFlag{
+							Name:  "path",
+							Value: "/",
+							Usage: "`PATH` requested from targets, e.g. path of liveness test",
+						},
+					),
+				},
+				{
+					Name:         "ip-addresses",
+					Usage:        "Gets test and target IPs for a domain and property. Run List GTM Properties for domain and property parameter values. PROPERTY - The Global Traffic Management property for which to collect IPs",
+					UsageText:    fmt.Sprintf("%s gtm ip-addresses --domain DOMAIN PROPERTY", appName),
+					Action:       cmdListGTMIPAddresses,
+					BashComplete: completeWith(gtmPropertyNames, map[string]func() []string{"domain": gtmDomains}),
 					Flags: []cli.Flag{
 						cli.StringFlag{
 							Name:  "domain",
@@ -94,26 +225,28 @@ func main() {
 		},
 		{
 			Name:  "ip",
-			Usage: "IP addresses related actions, like 'dig', 'curl', 'mtr', 'is cdn ip?' or 'ip geolocation' and so on",
+			Usage: "IP addresses (IPv4 or IPv6) related actions, like 'dig', 'curl', 'mtr', 'is cdn ip?' or 'ip geolocation' and so on",
 			Subcommands: []cli.Command{
 				{
 					Name:      "is-cdn-ip",
-					UsageText: fmt.Sprintf("%s ip is-cdn-ip IP_ADDRESS", appName),
+					UsageText: fmt.Sprintf("%s ip is-cdn-ip IP_ADDRESS|--input FILE", appName),
 					Usage:     "Checks whether the specified ip address is part of the Akamai edge network",
 					Action:    cmdCDNStatus,
+					Flags:     append(batchFlags(), workersFlag()),
 				},
 				{
 					Name:      "geolocation",
 					Usage:     "Provides the geolocation for an ip address within the Akamai network. This operation’s requests are limited to 500 per day",
-					UsageText: fmt.Sprintf("%s ip geolocation IP_ADDRESS", appName),
+					UsageText: fmt.Sprintf("%s ip geolocation IP_ADDRESS|--input FILE", appName),
 					Action:    cmdIPGeolocation,
+					Flags:     append(batchFlags(), workersFlag()),
 				},
 				{
 					Name:      "dig",
 					Usage:     "Run dig on a hostname to get DNS information, associating hostnames and IP addresses, from an IP address within the Akamai network not local to you",
-					UsageText: fmt.Sprintf("%s ip dig [command options] IP_ADDRESS", appName),
+					UsageText: fmt.Sprintf("%s ip dig [command options] IP_ADDRESS|--input FILE", appName),
 					Action:    cmdIPDig,
-					Flags: []cli.Flag{
+					Flags: append(append(append(batchFlags(), workersFlag()), digOutputFlags()...),
 						cli.StringFlag{
 							Name:  "hostname",
 							Value: "",
@@ -124,14 +257,14 @@ func main() {
 							Value: "A",
 							Usage: "The type of DNS record, either A, AAAA, CNAME, MX, NS, PTR, or SOA. The default is A",
 						},
-					},
+					),
 				},
 				{
 					Name:      "mtr",
 					Usage:     "Run mtr to check connectivity between a domain and an IP address within the Akamai network",
-					UsageText: fmt.Sprintf("%s ip mtr [command options] IP_ADDRESS", appName),
+					UsageText: fmt.Sprintf("%s ip mtr [command options] IP_ADDRESS|--input FILE", appName),
 					Action:    cmdIPMtr,
-					Flags: []cli.Flag{
+					Flags: append(append(batchFlags(), workersFlag()),
 						cli.StringFlag{
 							Name:  "destination-domain",
 							Value: "",
@@ -141,25 +274,14 @@ func main() {
 							Name:  "resolve-dns",
 							Usage: "Whether to use DNS to resolve hostnames. When disabled, output features only IP addresses",
 						},
-					},
+					),
 				},
 				{
 					Name:      "curl",
 					Usage:     "Run curl based on an IP address within the Akamai network. In the request object, specify a url to download and userAgent",
-					UsageText: fmt.Sprintf("%s ip curl [command options] IP_ADDRESS", appName),
+					UsageText: fmt.Sprintf("%s ip curl [command options] IP_ADDRESS|--input FILE", appName),
 					Action:    cmdIPCurl,
-					Flags: []cli.Flag{
-						cli.StringFlag{
-							Name:  "url",
-							Value: "",
-							Usage: "The URL for which to gather a curl response",
-						},
-						cli.StringFlag{
-							Name:  "user-agent",
-							Value: "Chrome",
-							Usage: "A header field to spoof a type of browser",
-						},
-					},
+					Flags:     append(append(batchFlags(), workersFlag()), curlFlags()...),
 				},
 			},
 		},
@@ -168,11 +290,12 @@ func main() {
 			Usage: "Ghost Location related actions, like 'dig', 'curl', 'mtr'",
 			Subcommands: []cli.Command{
 				{
-					Name:      "dig",
-					Usage:     "Run dig on a hostname to get DNS information, associating hostnames and IP addresses, from a location within the Akamai network not local to you. Specify location",
-					UsageText: fmt.Sprintf("%s ghost dig [command options] GHOST_LOCATION", appName),
-					Action:    cmdGhostDig,
-					Flags: []cli.Flag{
+					Name:         "dig",
+					Usage:        "Run dig on a hostname to get DNS information, associating hostnames and IP addresses, from a location within the Akamai network not local to you. Specify location",
+					UsageText:    fmt.Sprintf("%s ghost dig [command options] GHOST_LOCATION|--locations LOCATIONS|--near IP|--input FILE", appName),
+					Action:       cmdGhostDig,
+					BashComplete: completeGhostLocations(),
+					Flags: append(append(append(append(ghostLocationFlags(), nearFlag()), batchFlags()...), digOutputFlags()...),
 						cli.StringFlag{
 							Name:  "hostname",
 							Value: "",
@@ -183,20 +306,36 @@ func main() {
 							Value: "A",
 							Usage: "The type of DNS record, either A, AAAA, CNAME, MX, NS, PTR, or SOA. The default is A",
 						},
-					},
+					),
 				},
 				{
 					Name:      "locations",
 					Usage:     "Lists active Akamai edge server locations from which you can run diagnostic tools",
-					UsageText: fmt.Sprintf("%s ghost locations", appName),
+					UsageText: fmt.Sprintf("%s ghost locations [command options]", appName),
 					Action:    cmdGhostListLocations,
+					Flags:     locationFilterFlags(),
+					Subcommands: []cli.Command{
+						{
+							Name:      "nearest",
+							Usage:     "Find ghost location nearest to IP address, e.g. of a customer, using its geolocation",
+							UsageText: fmt.Sprintf("%s ghost locations nearest --ip IP_ADDRESS", appName),
+							Action:    cmdGhostNearestLocation,
+							Flags: []cli.Flag{
+								cli.StringFlag{
+									Name:  "ip",
+									Usage: "`IP` address to find the nearest ghost location for",
+								},
+							},
+						},
+					},
 				},
 				{
-					Name:      "mtr",
-					Usage:     "Run mtr to check connectivity between a domain and a location within the Akamai network not local to you. Specify location",
-					UsageText: fmt.Sprintf("%s ghost mtr [command options] GHOST_LOCATION", appName),
-					Action:    cmdGhostMtr,
-					Flags: []cli.Flag{
+					Name:         "mtr",
+					Usage:        "Run mtr to check connectivity between a domain and a location within the Akamai network not local to you. Specify location",
+					UsageText:    fmt.Sprintf("%s ghost mtr [command options] GHOST_LOCATION|--locations LOCATIONS|--near IP|--input FILE", appName),
+					Action:       cmdGhostMtr,
+					BashComplete: completeGhostLocations(),
+					Flags: append(append(append(ghostLocationFlags(), nearFlag()), batchFlags()...),
 						cli.StringFlag{
 							Name:  "destination-domain",
 							Value: "",
@@ -206,28 +345,115 @@ func main() {
 							Name:  "resolve-dns",
 							Usage: "Whether to use DNS to resolve hostnames. When disabled, output features only IP addresses",
 						},
-					},
+					),
 				},
 				{
-					Name:      "curl",
-					Usage:     "Run curl based on a location within the Akamai network. Specify location. In the request object, specify a url to download and userAgent",
-					UsageText: fmt.Sprintf("%s ghost curl [command options] GHOST_LOCATION", appName),
-					Action:    cmdGhostCurl,
-					Flags: []cli.Flag{
+					Name:         "curl",
+					Usage:        "Run curl based on a location within the Akamai network. Specify location. In the request object, specify a url to download and userAgent",
+					UsageText:    fmt.Sprintf("%s ghost curl [command options] GHOST_LOCATION|--locations LOCATIONS|--near IP|--input FILE", appName),
+					Action:       cmdGhostCurl,
+					BashComplete: completeGhostLocations(),
+					Flags:        append(append(append(ghostLocationFlags(), nearFlag()), batchFlags()...), curlFlags()...),
+				},
+				{
+					Name:         "origin-check",
+					Usage:        "Run dig and mtr to origin from many locations and summarize DNS resolution, packet loss and latency per region, flagging regions from which origin is not reachable, e.g. because its firewall blocks Akamai ranges",
+					UsageText:    fmt.Sprintf("%s ghost origin-check [command options] --origin HOST [--locations LOCATIONS]", appName),
+					Action:       cmdGhostOriginCheck,
+					BashComplete: completeWith(nil, map[string]func() []string{"locations": ghostLocationIDs}),
+					Flags: append(ghostLocationFlags(),
 						cli.StringFlag{
-							Name:  "url",
+							Name:  "origin",
 							Value: "",
-							Usage: "The URL for which to gather a curl response",
+							Usage: "Origin `HOST` to check, locations default to one per continent",
 						},
 						cli.StringFlag{
-							Name:  "user-agent",
-							Value: "Chrome",
-							Usage: "A header field to spoof a type of browser",
+							Name:  "query-type",
+							Value: "A",
+							Usage: "The type of DNS record to resolve origin with, either A or AAAA. The default is A",
 						},
-					},
+					),
+				},
+				{
+					Name:         "compare-curl",
+					Usage:        "Run curl from many locations and compare status code, content length, ETag, Last-Modified and cache status, highlighting locations which differ",
+					UsageText:    fmt.Sprintf("%s ghost compare-curl [command options] --locations LOCATIONS URL", appName),
+					Action:       cmdGhostCompareCurl,
+					BashComplete: completeWith(nil, map[string]func() []string{"locations": ghostLocationIDs}),
+					Flags:        append(ghostLocationFlags(), curlRequestFlags()...),
+				},
+				{
+					Name:         "compare-dig",
+					Usage:        "Run dig from many locations and compare CNAME chains and address records, highlighting locations which differ",
+					UsageText:    fmt.Sprintf("%s ghost compare-dig [command options] --locations LOCATIONS HOSTNAME", appName),
+					Action:       cmdGhostCompareDig,
+					BashComplete: completeWith(nil, map[string]func() []string{"locations": ghostLocationIDs}),
+					Flags: append(ghostLocationFlags(),
+						cli.StringFlag{
+							Name:  "query-type",
+							Value: "A",
+							Usage: "The type of DNS record, either A, AAAA, CNAME, MX, NS, PTR, or SOA. The default is A",
+						},
+					),
 				},
 			},
 		},
+		{
+			Name:         "completion",
+			Usage:        "Print completion script for bash, zsh or fish, which completes commands, flags and values like ghost locations or GTM properties",
+			UsageText:    fmt.Sprintf("%s completion [command options] bash|zsh|fish", appName),
+			Action:       cmdCompletion,
+			BashComplete: completeWith(completionShells, nil),
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "name",
+					Usage: "Complete command `NAME` instead of name the tool was run with, e.g. when it is run through an alias",
+				},
+			},
+		},
+		{
+			Name:  "config",
+			Usage: "Check credentials used by the tool",
+			Subcommands: []cli.Command{
+				{
+					Name:      "list-sections",
+					Usage:     "List sections of credentials file given with --config together with fields missing in them",
+					UsageText: fmt.Sprintf("%s config list-sections", appName),
+					Action:    cmdConfigListSections,
+				},
+				{
+					Name:      "verify",
+					Usage:     "Check that sections of credentials file have all fields and that API accepts them for Diagnostic Tools. Without SECTION all sections are checked",
+					UsageText: fmt.Sprintf("%s config verify [SECTION...]", appName),
+					Action:    cmdConfigVerify,
+				},
+				{
+					Name:      "show",
+					Usage:     "Show where credentials are loaded from, their host and account switch keys, with secrets redacted",
+					UsageText: fmt.Sprintf("%s config show", appName),
+					Action:    cmdConfigShow,
+				},
+			},
+		},
+		{
+			Name:         "diagnose",
+			Usage:        "Check hostname end to end: resolve it from ghost locations, check addresses are Akamai edge IPs, curl URL from them and run mtr to origin, finishing with pass, warn or fail per check",
+			UsageText:    fmt.Sprintf("%s diagnose [command options] HOSTNAME", appName),
+			Action:       cmdDiagnose,
+			BashComplete: completeWith(nil, map[string]func() []string{"locations": ghostLocationIDs}),
+			Flags: append(append(ghostLocationFlags(), curlRequestFlags()...),
+				cli.StringFlag{
+					Name:  "url",
+					Value: "",
+					Usage: "`URL` to request from edge IPs, https://HOSTNAME/ by default",
+				},
+				cli.StringFlag{
+					Name:  "origin",
+					Value: "",
+					Usage: "Origin `HOST` to run mtr to, by default read from cache key returned by edge servers",
+				},
+			),
+		},
 		{
 			Name:  "diagnostic-link",
 			Usage: "Generate/List/Get a unique link to send to a user to diagnose a problem",
@@ -252,10 +478,11 @@ func main() {
 					Action:    cmdListLinkRequests,
 				},
 				{
-					Name:      "get",
-					Usage:     "Gets details on IP addresses used for an end user’s diagnostic link test",
-					UsageText: fmt.Sprintf("%s diagnostic-link get [command options] REQUEST_ID", appName),
-					Action:    cmdGetLinkDetails,
+					Name:         "get",
+					Usage:        "Gets details on IP addresses used for an end user’s diagnostic link test",
+					UsageText:    fmt.Sprintf("%s diagnostic-link get [command options] REQUEST_ID", appName),
+					Action:       cmdGetLinkDetails,
+					BashComplete: completeWith(diagnosticLinkIDs, nil),
 				},
 			},
 		},
@@ -264,37 +491,113 @@ func main() {
 	sort.Sort(cli.FlagsByName(app.Flags))
 	sort.Sort(cli.CommandsByName(app.Commands))
 
-	app.Before = func(c *cli.Context) error {
+	app.OnUsageError = usageError
+	handleUsageErrors(app.Commands)
 
-		var creds *edgegrid.Credentials
+	app.Before = func(c *cli.Context) error {
+		if err := validateGlobalFlags(c); err != nil {
+			return err
+		}
 
-		if c.GlobalString("config") != common.HomeDir() {
-			var err error
-			creds, err = edgegrid.NewCredentials().FromFile(c.GlobalString("config")).Section(c.GlobalString("section"))
+		// Completion scripts are printed and credentials are checked without loading credentials first
+		if c.Args().First() == "completion" || c.Args().First() == "config" {
+			return nil
+		}
 
-			if err != nil {
-				return err
-			}
-		} else {
-			creds = edgegrid.NewCredentials().AutoLoad(c.GlobalString("section"))
+		// Credential helper might ask for passphrase, which would block completion
+		if completing(os.Args) && c.GlobalString("credential-helper") != "" {
+			return nil
 		}
 
-		if creds == nil {
-			return fmt.Errorf("Cannot load credentials")
+		err := initAPIClients(c)
+
+		// Completion works from local cache alone when credentials are missing
+		if err != nil && completing(os.Args) {
+			apiClient, accountClients = nil, nil
+			return nil
 		}
 
-		config := edgegrid.NewConfig().WithCredentials(creds).WithLogVerbosity(c.GlobalString("debug")).WithAccountSwitchKey(c.GlobalString("ask"))
-		if c.GlobalString("debug") == "debug" {
-			config = config.WithRequestDebug(true)
+		return err
+	}
+
+	return app
+}
+
+// usageError prints help as cli does for flags which cannot be parsed, but
+// returns validation error, so the tool does not exit with generic failure
+func usageError(c *cli.Context, err error, isSubcommand bool) error {
+	fmt.Fprintf(c.App.Writer, "Incorrect Usage: %s\n\n", err)
+
+	switch {
+	case isSubcommand:
+		cli.ShowSubcommandHelp(c)
+	case c.Command.Name != "":
+		cli.ShowCommandHelp(c, c.Command.Name)
+	default:
+		cli.ShowAppHelp(c)
+	}
+
+	return newValidationError("Incorrect Usage: %s", err)
+}
+
+// handleUsageErrors sets usageError for commands and their subcommands
+func handleUsageErrors(commands []cli.Command) {
+	for i := range commands {
+		commands[i].OnUsageError = usageError
+		handleUsageErrors(commands[i].Subcommands)
+	}
+}
+
+// validateGlobalFlags checks global flags before credentials are loaded
+// and any API call is made
+func validateGlobalFlags(c *cli.Context) error {
+	return validateOutputFormat(c.GlobalString("output"))
+}
+
+// initAPIClients creates API client, or one client per account when --ask
+// lists many account switch keys
+func initAPIClients(c *cli.Context) error {
+	keys, err := parseAccountSwitchKeys(c.GlobalString("ask"))
+	if err != nil {
+		return err
+	}
+
+	creds, source, err := loadCredentials(c)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Using credentials from %s", source)
+
+	if len(keys) <= 1 {
+		key := ""
+		if len(keys) == 1 {
+			key = keys[0]
 		}
-		// Provide struct details needed for apiClient init
-		apiClient = service.New(config)
 
+		apiClient = newAPIClient(c, creds, key)
 		return nil
 	}
 
-	err := app.Run(os.Args)
-	if err != nil {
-		log.Fatal(err)
+	if !multiAccountCommand(c.Args()) {
+		return newValidationError("Many account switch keys can be used only with: %s", strings.Join(multiAccountCommands, ", "))
 	}
+
+	log.Debugf("Running against %d accounts", len(keys))
+
+	for _, key := range keys {
+		accountClients = append(accountClients, accountClient{key: key, client: newAPIClient(c, creds, key)})
+	}
+
+	return nil
+}
+
+// newAPIClient creates API client for single account switch key
+func newAPIClient(c *cli.Context, creds *edgegrid.Credentials, accountSwitchKey string) diagnosticClient {
+	config := edgegrid.NewConfig().WithCredentials(creds).WithLogVerbosity(c.GlobalString("debug")).WithAccountSwitchKey(accountSwitchKey)
+	if c.GlobalString("debug") == "debug" {
+		config = config.WithRequestDebug(true)
+	}
+
+	return newDiagnosticClient(config)
 }
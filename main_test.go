@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(ioutil.Discard)
+
+	// Keep local state of the tool away from user config directory
+	dir, err := ioutil.TempDir("", "diagnostic-tools-state")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv(stateDirEnvVar, dir)
+
+	code := m.Run()
+
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// runCommand executes application with given arguments against fake API
+// and returns everything rendered to output
+func runCommand(t *testing.T, api *fakeAPI, args ...string) (string, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+	outputWriter = &out
+	defer func() { outputWriter = os.Stdout }()
+
+	apiClient = api.client()
+
+	app := newApp()
+	app.Before = validateGlobalFlags
+	app.Writer = ioutil.Discard
+	app.ErrWriter = ioutil.Discard
+
+	err := app.Run(append([]string{"akamai-cli-diagnostic-tools"}, args...))
+
+	return out.String(), err
+}
+
+// commandTest describes single command invocation and its expected outcome
+type commandTest struct {
+	name     string
+	args     []string
+	exitCode int
+	contains []string
+}
+
+func runCommandTests(t *testing.T, tests []commandTest) {
+	t.Helper()
+
+	api := newFakeAPI()
+	defer api.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := runCommand(t, api, tt.args...)
+
+			if code := exitCode(err); code != tt.exitCode {
+				t.Fatalf("exit code = %d, want %d (error: %v)", code, tt.exitCode, err)
+			}
+
+			for _, s := range tt.contains {
+				if !strings.Contains(out, s) {
+					t.Errorf("output does not contain %q:\n%s", s, out)
+				}
+			}
+		})
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name:     "unknown global flag",
+			args:     []string{"--no-such-flag", "ghost", "locations"},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "unknown command flag",
+			args:     []string{"ghost", "dig", "--no-such-flag", "frankfurt-germany"},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "invalid number",
+			args:     []string{"ghost", "dig", "--hostname", "www.example.com", "--locations", "all", "--workers", "many"},
+			exitCode: exitValidation,
+		},
+	})
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	service "github.com/apiheat/go-edgegrid/v6/service/diagnosticv2"
+	log "github.com/sirupsen/logrus"
+)
+
+// mtrHopLine matches hop line of mtr report, e.g.
+// '  2.|-- 192.0.2.1   0.0%    10   10.4  10.5  10.1  11.2   0.3'
+var mtrHopLine = regexp.MustCompile(`^\s*(\d+)\.\s*\|--\s+(\S+)\s+([\d.]+)%?\s+(\d+)\s+([\d.]+)\s+([\d.]+)\s+([\d.]+)\s+([\d.]+)\s+([\d.]+)\s*$`)
+
+// unknownMtrHost is shown by mtr for hops which did not answer
+const unknownMtrHost = "???"
+
+// mtrHop is single hop of mtr, latencies are in milliseconds. Jump is increase
+// of average latency compared to previous hop which answered.
+type mtrHop struct {
+	Number int     `json:"number"`
+	Host   string  `json:"host"`
+	Loss   float64 `json:"loss"`
+	Sent   int     `json:"sent"`
+	Last   float64 `json:"last"`
+	Avg    float64 `json:"avg"`
+	Best   float64 `json:"best"`
+	Worst  float64 `json:"worst"`
+	StDev  float64 `json:"stDev"`
+	Jump   float64 `json:"jump"`
+}
+
+// mtrFindings points at the most interesting parts of mtr
+type mtrFindings struct {
+	DestinationReached bool    `json:"destinationReached"`
+	FirstLossHop       int     `json:"firstLossHop,omitempty"`
+	FirstLossHost      string  `json:"firstLossHost,omitempty"`
+	FirstLoss          float64 `json:"firstLoss,omitempty"`
+	LargestJumpHop     int     `json:"largestJumpHop,omitempty"`
+	LargestJumpHost    string  `json:"largestJumpHost,omitempty"`
+	LargestJump        float64 `json:"largestJump,omitempty"`
+}
+
+// mtrReport is mtr result with typed hops and findings derived from them
+type mtrReport struct {
+	Source      string      `json:"source"`
+	Destination string      `json:"destination"`
+	Host        string      `json:"host"`
+	StartTime   time.Time   `json:"startTime"`
+	PacketLoss  float64     `json:"packetLoss"`
+	AvgLatency  float64     `json:"avgLatency"`
+	Analysis    string      `json:"analysis,omitempty"`
+	Hops        []mtrHop    `json:"hops"`
+	Findings    mtrFindings `json:"findings"`
+}
+
+func (r mtrReport) tableHeader() []string {
+	return []string{"hop", "host", "loss%", "sent", "last", "avg", "best", "worst", "stDev", "jump"}
+}
+
+func (r mtrReport) tableRows() [][]string {
+	var rows [][]string
+	for _, h := range r.Hops {
+		rows = append(rows, []string{
+			strconv.Itoa(h.Number),
+			h.Host,
+			formatFloat(h.Loss),
+			strconv.Itoa(h.Sent),
+			formatFloat(h.Last),
+			formatFloat(h.Avg),
+			formatFloat(h.Best),
+			formatFloat(h.Worst),
+			formatFloat(h.StDev),
+			formatFloat(h.Jump),
+		})
+	}
+
+	return rows
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 1, 64)
+}
+
+// newMtrReport converts API response into mtr report. Older responses carry
+// hops only inside text of 'result', those are parsed from it.
+func newMtrReport(response *service.MtrResult) mtrReport {
+	mtr := response.Mtr
+
+	report := mtrReport{
+		Source:      mtr.Source,
+		Destination: mtr.Destination,
+		Host:        mtr.Host,
+		StartTime:   mtr.StartTime,
+		PacketLoss:  mtr.PacketLoss,
+		AvgLatency:  mtr.AvgLatency,
+		Analysis:    mtr.Analysis,
+		Hops:        []mtrHop{},
+	}
+
+	for _, h := range mtr.Hops {
+		report.Hops = append(report.Hops, mtrHop{
+			Number: h.Number,
+			Host:   h.Host,
+			Loss:   h.Loss,
+			Sent:   h.Sent,
+			Last:   h.Last,
+			Avg:    h.Avg,
+			Best:   h.Best,
+			Worst:  h.Worst,
+			StDev:  h.StDev,
+		})
+	}
+
+	if len(report.Hops) == 0 && mtr.Result != "" {
+		report.Hops = parseMtrHops(mtr.Result)
+	}
+
+	report.Findings = analyseMtrHops(report.Hops)
+
+	return report
+}
+
+// parseMtrHops reads hops from mtr text report
+func parseMtrHops(text string) []mtrHop {
+	hops := []mtrHop{}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		m := mtrHopLine.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+
+		hop := mtrHop{Host: m[2]}
+		hop.Number, _ = strconv.Atoi(m[1])
+		hop.Loss, _ = strconv.ParseFloat(m[3], 64)
+		hop.Sent, _ = strconv.Atoi(m[4])
+		hop.Last, _ = strconv.ParseFloat(m[5], 64)
+		hop.Avg, _ = strconv.ParseFloat(m[6], 64)
+		hop.Best, _ = strconv.ParseFloat(m[7], 64)
+		hop.Worst, _ = strconv.ParseFloat(m[8], 64)
+		hop.StDev, _ = strconv.ParseFloat(m[9], 64)
+
+		hops = append(hops, hop)
+	}
+
+	return hops
+}
+
+func answered(h mtrHop) bool {
+	return h.Host != unknownMtrHost && h.Loss < 100
+}
+
+// analyseMtrHops fills latency jumps of hops and finds first hop with loss,
+// the largest jump and whether the last hop, the destination, answered
+func analyseMtrHops(hops []mtrHop) mtrFindings {
+	var findings mtrFindings
+
+	previous := -1
+	for i := range hops {
+		h := &hops[i]
+
+		if h.Loss > 0 && findings.FirstLossHop == 0 {
+			findings.FirstLossHop = h.Number
+			findings.FirstLossHost = h.Host
+			findings.FirstLoss = h.Loss
+		}
+
+		if !answered(*h) {
+			continue
+		}
+
+		if previous >= 0 {
+			h.Jump = h.Avg - hops[previous].Avg
+			if h.Jump > findings.LargestJump {
+				findings.LargestJump = h.Jump
+				findings.LargestJumpHop = h.Number
+				findings.LargestJumpHost = h.Host
+			}
+		}
+		previous = i
+	}
+
+	if len(hops) > 0 {
+		findings.DestinationReached = answered(hops[len(hops)-1])
+	}
+
+	return findings
+}
+
+// summary describes findings in one line for humans
+func (f mtrFindings) summary() string {
+	var parts []string
+
+	if f.DestinationReached {
+		parts = append(parts, "destination reached")
+	} else {
+		parts = append(parts, "destination NOT reached")
+	}
+
+	if f.FirstLossHop > 0 {
+		parts = append(parts, fmt.Sprintf("first loss %s%% at hop %d ( %s )", formatFloat(f.FirstLoss), f.FirstLossHop, f.FirstLossHost))
+	} else {
+		parts = append(parts, "no loss")
+	}
+
+	if f.LargestJumpHop > 0 {
+		parts = append(parts, fmt.Sprintf("largest latency jump +%sms at hop %d ( %s )", formatFloat(f.LargestJump), f.LargestJumpHop, f.LargestJumpHost))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// mtrOutput builds mtr report and reports its findings on stderr
+func mtrOutput(response *service.MtrResult) mtrReport {
+	report := newMtrReport(response)
+
+	if report.Findings.DestinationReached {
+		log.Infof("mtr %s -> %s: %s", report.Source, report.Destination, report.Findings.summary())
+	} else {
+		log.Warnf("mtr %s -> %s: %s", report.Source, report.Destination, report.Findings.summary())
+	}
+
+	return report
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const mtrText = `Start: Sun Oct 18 08:00:00 2026
+HOST: a23-15-7-10.deploy.static.akamaitechnologies.com Loss%   Snt   Last   Avg  Best  Wrst StDev
+  1.|-- 10.0.0.1                 0.0%    10    0.5   0.5   0.4   0.7   0.1
+  2.|-- ???                     100.0    10    0.0   0.0   0.0   0.0   0.0
+  3.|-- 192.0.2.1               20.0%    10   30.4  30.5  30.1  31.2   0.3
+  4.|-- 192.0.2.2                0.0%    10   32.0  31.0  30.8  33.0   0.5
+`
+
+func TestParseMtrHops(t *testing.T) {
+	hops := parseMtrHops(mtrText)
+
+	want := []mtrHop{
+		{Number: 1, Host: "10.0.0.1", Loss: 0, Sent: 10, Last: 0.5, Avg: 0.5, Best: 0.4, Worst: 0.7, StDev: 0.1},
+		{Number: 2, Host: "???", Loss: 100, Sent: 10},
+		{Number: 3, Host: "192.0.2.1", Loss: 20, Sent: 10, Last: 30.4, Avg: 30.5, Best: 30.1, Worst: 31.2, StDev: 0.3},
+		{Number: 4, Host: "192.0.2.2", Loss: 0, Sent: 10, Last: 32, Avg: 31, Best: 30.8, Worst: 33, StDev: 0.5},
+	}
+
+	if !reflect.DeepEqual(hops, want) {
+		t.Errorf("parseMtrHops() = %+v, want %+v", hops, want)
+	}
+}
+
+func TestAnalyseMtrHops(t *testing.T) {
+	tests := []struct {
+		name string
+		hops []mtrHop
+		want mtrFindings
+	}{
+		{
+			name: "loss and jump",
+			hops: parseMtrHops(mtrText),
+			want: mtrFindings{
+				DestinationReached: true,
+				FirstLossHop:       2,
+				FirstLossHost:      "???",
+				FirstLoss:          100,
+				LargestJumpHop:     3,
+				LargestJumpHost:    "192.0.2.1",
+				LargestJump:        30,
+			},
+		},
+		{
+			name: "destination not reached",
+			hops: []mtrHop{{Number: 1, Host: "10.0.0.1", Avg: 1}, {Number: 2, Host: "???", Loss: 100}},
+			want: mtrFindings{FirstLossHop: 2, FirstLossHost: "???", FirstLoss: 100},
+		},
+		{
+			name: "no hops",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := analyseMtrHops(tt.hops); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("analyseMtrHops() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMtrOutput(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name:     "findings",
+			args:     []string{"ip", "mtr", "--destination-domain", "www.example.com", "23.15.7.10"},
+			contains: []string{`"findings": {`, `"destinationReached": true`, `"largestJumpHop": 2`, `"jump": 10`},
+		},
+		{
+			name:     "hop table",
+			args:     []string{"--output", "table", "ghost", "mtr", "--destination-domain", "www.example.com", "paris-france"},
+			contains: []string{"HOP", "LOSS%", "STDEV", "JUMP", "192.0.2.1", "10.5"},
+		},
+	})
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+func cmdGhostOriginCheck(c *cli.Context) error {
+	return ghostOriginCheck(c)
+}
+
+// Reachability of origin from region. Region is in error when mtr API call
+// failed from all its locations, so reachability is not known.
+const (
+	regionReachable   = "reachable"
+	regionPartial     = "partial"
+	regionUnreachable = "unreachable"
+	regionError       = "error"
+)
+
+// unknownRegion groups ghost locations which continent is not known
+const unknownRegion = "unknown"
+
+// originProbe is outcome of dig and mtr to origin from single ghost location
+type originProbe struct {
+	Location   string   `json:"location"`
+	Region     string   `json:"region"`
+	Resolved   bool     `json:"resolved"`
+	Addresses  []string `json:"addresses,omitempty"`
+	DNSError   string   `json:"dnsError,omitempty"`
+	Reached    bool     `json:"reached"`
+	PacketLoss float64  `json:"packetLoss"`
+	AvgLatency float64  `json:"avgLatency"`
+	Findings   string   `json:"findings,omitempty"`
+	MtrError   string   `json:"mtrError,omitempty"`
+}
+
+// regionReachability summarizes origin probes of all ghost locations in
+// region. Packet loss is averaged over locations where mtr ran and latency
+// over locations which reached origin. Locations where mtr failed are counted
+// in Errors only, they are neither reached nor unreachable.
+type regionReachability struct {
+	Region      string   `json:"region"`
+	Status      string   `json:"status"`
+	Locations   int      `json:"locations"`
+	Resolved    int      `json:"resolved"`
+	Reached     int      `json:"reached"`
+	Errors      int      `json:"errors"`
+	PacketLoss  float64  `json:"packetLoss"`
+	AvgLatency  float64  `json:"avgLatency"`
+	Unreachable []string `json:"unreachable,omitempty"`
+}
+
+// originCheck tells from which regions of Akamai network origin is reachable
+type originCheck struct {
+	Origin             string               `json:"origin"`
+	UnreachableRegions []string             `json:"unreachableRegions"`
+	Regions            []regionReachability `json:"regions"`
+	Locations          []originProbe        `json:"locations"`
+}
+
+func (r originCheck) tableHeader() []string {
+	return []string{"region", "status", "dns", "reached", "errors", "packetLoss", "avgLatency", "unreachable"}
+}
+
+func (r originCheck) tableRows() [][]string {
+	var rows [][]string
+	for _, region := range r.Regions {
+		rows = append(rows, []string{
+			region.Region,
+			region.Status,
+			fmt.Sprintf("%d/%d", region.Resolved, region.Locations),
+			fmt.Sprintf("%d/%d", region.Reached, region.Locations),
+			strconv.Itoa(region.Errors),
+			formatFloat(region.PacketLoss),
+			formatFloat(region.AvgLatency),
+			strings.Join(region.Unreachable, ", "),
+		})
+	}
+
+	return rows
+}
+
+// ghostOriginCheck runs dig and mtr to origin from every ghost location and
+// summarizes them per region, flagging regions which cannot reach origin.
+// It fails with checkFailedError when any region cannot reach origin.
+func ghostOriginCheck(c *cli.Context) error {
+	if c.String("origin") == "" {
+		return newFlagError("Please provide --origin HOST to check")
+	}
+
+	// Query type selects addresses dig resolves origin to, mtr always
+	// targets origin hostname, so it does not change reachability
+	if qt := c.String("query-type"); qt != "A" && qt != "AAAA" {
+		return newQueryTypeError("Provide correct 'query-type': A or AAAA")
+	}
+
+	f := rowFlags{c: c, row: map[string]string{"hostname": c.String("origin"), "destination-domain": c.String("origin")}}
+	if err := validateDigOptions(f); err != nil {
+		return err
+	}
+
+	locations, err := selectGhostLocations(c)
+	if err != nil {
+		return err
+	}
+
+	regions := locationRegions()
+
+	// Probes are kept also for locations where both API calls failed, as
+	// fanOut records only error of those
+	var (
+		mu     sync.Mutex
+		probes = map[string]originProbe{}
+	)
+
+	report := fanOut(locations, c.Int("workers"), func(location string) (interface{}, error) {
+		probe, err := probeOrigin(location, regionOf(regions, location), f)
+
+		mu.Lock()
+		probes[location] = probe
+		mu.Unlock()
+
+		return probe, err
+	})
+
+	result := originCheck{Origin: c.String("origin"), UnreachableRegions: []string{}}
+	for _, location := range report.locations() {
+		result.Locations = append(result.Locations, probes[location])
+	}
+
+	result.Regions = summarizeRegions(result.Locations)
+	for _, region := range result.Regions {
+		if region.Status == regionUnreachable {
+			result.UnreachableRegions = append(result.UnreachableRegions, region.Region)
+		}
+	}
+
+	if err := printOutput(c, result); err != nil {
+		return err
+	}
+
+	if err := report.failure(); err != nil {
+		return err
+	}
+
+	if len(result.UnreachableRegions) > 0 {
+		return checkFailedError{msg: fmt.Sprintf("Origin %s is not reachable from %s", result.Origin, strings.Join(result.UnreachableRegions, ", "))}
+	}
+
+	return nil
+}
+
+// probeOrigin digs and runs mtr to origin from ghost location. Failures of
+// API calls are recorded in probe, error is returned only when both failed,
+// so nothing is known about origin from the location.
+func probeOrigin(location, region string, f flagSource) (originProbe, error) {
+	probe := originProbe{Location: location, Region: region}
+
+	dig, digErr := apiClient.ExecuteDig(location, requestFromGhost, f.String("hostname"), f.String("query-type"))
+	if digErr != nil {
+		probe.DNSError = errorText(newAPIError(digErr))
+	} else {
+		probe.Addresses = newDigReport(dig).addresses()
+		probe.Resolved = len(probe.Addresses) > 0
+	}
+
+	mtr, err := apiClient.ExecuteMtr(location, requestFromGhost, f.String("destination-domain"), false)
+	if err != nil {
+		probe.MtrError = errorText(newAPIError(err))
+		if digErr != nil {
+			return probe, err
+		}
+
+		return probe, nil
+	}
+
+	report := newMtrReport(mtr)
+	probe.Reached = report.Findings.DestinationReached
+	probe.PacketLoss = report.PacketLoss
+	probe.AvgLatency = report.AvgLatency
+	probe.Findings = report.Findings.summary()
+
+	return probe, nil
+}
+
+// summarizeRegions groups probes by region, regions are sorted by name
+func summarizeRegions(probes []originProbe) []regionReachability {
+	byRegion := map[string]*regionReachability{}
+	latencies := map[string]int{}
+	measured := map[string]int{}
+
+	for _, p := range probes {
+		region, ok := byRegion[p.Region]
+		if !ok {
+			region = &regionReachability{Region: p.Region}
+			byRegion[p.Region] = region
+		}
+
+		region.Locations++
+		if p.Resolved {
+			region.Resolved++
+		}
+
+		if p.MtrError != "" {
+			region.Errors++
+			continue
+		}
+
+		region.PacketLoss += p.PacketLoss
+		measured[p.Region]++
+
+		if p.Reached {
+			region.Reached++
+			region.AvgLatency += p.AvgLatency
+			latencies[p.Region]++
+		} else {
+			region.Unreachable = append(region.Unreachable, p.Location)
+		}
+	}
+
+	var names []string
+	for name := range byRegion {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var regions []regionReachability
+	for _, name := range names {
+		region := byRegion[name]
+
+		if measured[name] > 0 {
+			region.PacketLoss /= float64(measured[name])
+		}
+		if latencies[name] > 0 {
+			region.AvgLatency /= float64(latencies[name])
+		}
+
+		switch checked := region.Locations - region.Errors; {
+		case checked == 0:
+			region.Status = regionError
+		case region.Reached == checked:
+			region.Status = regionReachable
+		case region.Reached == 0:
+			region.Status = regionUnreachable
+		default:
+			region.Status = regionPartial
+		}
+
+		regions = append(regions, *region)
+	}
+
+	return regions
+}
+
+// locationRegions maps known ghost locations to their continent, which is
+// empty when country of location is not recognised
+func locationRegions() map[string]string {
+	regions := map[string]string{}
+
+	known, _, err := knownGhostLocations(false)
+	if err != nil {
+		log.Debugf("Cannot read ghost locations to find their regions: %s", err)
+		return regions
+	}
+
+	for _, l := range known {
+		regions[l.ID] = l.Continent
+	}
+
+	return regions
+}
+
+// regionOf returns region of ghost location or unknownRegion
+func regionOf(regions map[string]string, location string) string {
+	if region := regions[location]; region != "" {
+		return region
+	}
+
+	return unknownRegion
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSummarizeRegions(t *testing.T) {
+	probes := []originProbe{
+		{Location: "berlin-germany", Region: "EU", Resolved: true, Reached: true, PacketLoss: 10, AvgLatency: 20},
+		{Location: "paris-france", Region: "EU", Resolved: true, Reached: true, PacketLoss: 0, AvgLatency: 10},
+		{Location: "tokyo-japan", Region: "AS", Resolved: true, PacketLoss: 100},
+		{Location: "sydney-australia", Region: "OC", MtrError: "Internal Server Error"},
+		{Location: "osaka-japan", Region: "AS", Resolved: true, Reached: true, AvgLatency: 150},
+		{Location: "madrid-spain", Region: "EU", Resolved: true, MtrError: "Forbidden"},
+	}
+
+	want := []regionReachability{
+		{Region: "AS", Status: regionPartial, Locations: 2, Resolved: 2, Reached: 1, PacketLoss: 50, AvgLatency: 150, Unreachable: []string{"tokyo-japan"}},
+		{Region: "EU", Status: regionReachable, Locations: 3, Resolved: 3, Reached: 2, Errors: 1, PacketLoss: 5, AvgLatency: 15},
+		{Region: "OC", Status: regionError, Locations: 1, Errors: 1},
+	}
+
+	if got := summarizeRegions(probes); !reflect.DeepEqual(got, want) {
+		t.Errorf("summarizeRegions() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGhostOriginCheck(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name: "reachable origin",
+			args: []string{"ghost", "origin-check", "--origin", "origin.example.com"},
+			contains: []string{
+				`"unreachableRegions": []`,
+				`"region": "AS"`,
+				`"region": "EU"`,
+				`"status": "reachable"`,
+				`"addresses": [
+                "23.15.7.10"
+            ]`,
+			},
+		},
+		{
+			name: "firewalled region",
+			args: []string{"ghost", "origin-check", "--origin", fakeFirewalled + ".example.com", "--locations", "all"},
+			contains: []string{
+				`"unreachableRegions": [
+        "AS"
+    ]`,
+				`"status": "unreachable"`,
+				`"unreachable": [
+                "tokyo-japan"
+            ]`,
+			},
+			exitCode: exitCheckFailed,
+		},
+		{
+			name:     "api errors table",
+			args:     []string{"--output", "table", "ghost", "origin-check", "--origin", fakeBroken + ".example.com", "--locations", "*germany*"},
+			contains: []string{"REGION", "PACKETLOSS", "ERRORS", "EU", "error", "0/2"},
+			exitCode: exitAPI,
+		},
+		{
+			name:     "rejected credentials",
+			args:     []string{"ghost", "origin-check", "--origin", fakeForbidden + ".example.com", "--locations", "all"},
+			contains: []string{`"status": "error"`, `"mtrError": "Forbidden`},
+			exitCode: exitAuth,
+		},
+		{
+			name:     "without origin",
+			args:     []string{"ghost", "origin-check", "--locations", "all"},
+			exitCode: exitInvalidFlag,
+		},
+		{
+			name:     "aaaa query type",
+			args:     []string{"ghost", "origin-check", "--origin", "origin.example.com", "--query-type", "AAAA", "--locations", "paris-france"},
+			contains: []string{`"status": "reachable"`},
+		},
+		{
+			name:     "unsupported query type",
+			args:     []string{"ghost", "origin-check", "--origin", "origin.example.com", "--query-type", "CNAME", "--locations", "all"},
+			exitCode: exitInvalidQueryType,
+		},
+		{
+			name:     "unknown location",
+			args:     []string{"ghost", "origin-check", "--origin", "origin.example.com", "--locations", "frankfurt-germny"},
+			exitCode: exitValidation,
+		},
+	})
+}
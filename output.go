@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	common "github.com/apiheat/akamai-cli-common"
+	"github.com/urfave/cli"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	outputFormatJSON   = "json"
+	outputFormatTable  = "table"
+	outputFormatYAML   = "yaml"
+	outputFormatCSV    = "csv"
+	outputFormatNDJSON = "ndjson"
+)
+
+var (
+	outputFormats = []string{outputFormatJSON, outputFormatTable, outputFormatYAML, outputFormatCSV, outputFormatNDJSON}
+
+	// outputWriter is where all command results are rendered to
+	outputWriter io.Writer = os.Stdout
+)
+
+// tabular is implemented by results which know better than generic
+// flattening how they should look like as table or CSV
+type tabular interface {
+	tableHeader() []string
+	tableRows() [][]string
+}
+
+// printOutput renders command result using format selected with global 'output' flag
+func printOutput(c *cli.Context, input interface{}) error {
+	format := strings.ToLower(c.GlobalString("output"))
+	if format == "" {
+		format = outputFormatJSON
+	}
+
+	switch format {
+	case outputFormatJSON:
+		return renderJSON(outputWriter, input)
+	case outputFormatYAML:
+		return renderYAML(outputWriter, input)
+	case outputFormatNDJSON:
+		return renderNDJSON(outputWriter, input)
+	case outputFormatTable:
+		header, rows, err := tableData(input, true)
+		if err != nil {
+			return err
+		}
+		return renderTable(outputWriter, header, rows)
+	case outputFormatCSV:
+		header, rows, err := tableData(input, false)
+		if err != nil {
+			return err
+		}
+		return renderCSV(outputWriter, header, rows)
+	}
+
+	return validateOutputFormat(format)
+}
+
+// validateOutputFormat fails for formats printOutput cannot render, it is
+// checked before any API call is made
+func validateOutputFormat(format string) error {
+	if format != "" && !common.IsStringInSlice(strings.ToLower(format), outputFormats) {
+		return newFlagError("Unsupported output format '%s', use one of: %s", format, strings.Join(outputFormats, ", "))
+	}
+
+	return nil
+}
+
+func renderJSON(w io.Writer, input interface{}) error {
+	var prettyJSON bytes.Buffer
+	if err := json.Indent(&prettyJSON, []byte(outputJSON(input)), "", "    "); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintln(w, prettyJSON.String())
+	return err
+}
+
+func renderYAML(w io.Writer, input interface{}) error {
+	generic, err := toGeneric(input)
+	if err != nil {
+		return err
+	}
+
+	b, err := yaml.Marshal(generic)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// renderNDJSON writes one line per element of list or per value of map
+// keyed report, anything else is written as single line
+func renderNDJSON(w io.Writer, input interface{}) error {
+	var records []interface{}
+
+	v := reflect.ValueOf(input)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			records = append(records, v.Index(i).Interface())
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, k := range keys {
+			records = append(records, v.MapIndex(k).Interface())
+		}
+	default:
+		records = append(records, input)
+	}
+
+	for _, record := range records {
+		if _, err := fmt.Fprintln(w, outputJSON(record)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func renderTable(w io.Writer, header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	upper := make([]string, len(header))
+	for i, h := range header {
+		upper[i] = strings.ToUpper(h)
+	}
+	fmt.Fprintln(tw, strings.Join(upper, "\t"))
+
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func renderCSV(w io.Writer, header []string, rows [][]string) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// tableData converts any result into header and rows. Lists become one row
+// per element, nested objects are flattened into dotted column names. Single
+// objects become one row or, when vertical is set, one row per field which
+// reads much better in terminal.
+func tableData(input interface{}, vertical bool) ([]string, [][]string, error) {
+	if t, ok := input.(tabular); ok {
+		return t.tableHeader(), t.tableRows(), nil
+	}
+
+	generic, err := toGeneric(input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, single := generic.(map[string]interface{}); single && vertical {
+		flat := map[string]string{}
+		flatten("", generic, flat)
+
+		var rows [][]string
+		for k, v := range flat {
+			rows = append(rows, []string{k, v})
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i][0] < rows[j][0] })
+
+		return []string{"field", "value"}, rows, nil
+	}
+
+	var flattened []map[string]string
+	columns := map[string]bool{}
+	for _, record := range toRecords(generic) {
+		flat := map[string]string{}
+		flatten("", record, flat)
+		for k := range flat {
+			columns[k] = true
+		}
+		flattened = append(flattened, flat)
+	}
+
+	var header []string
+	for k := range columns {
+		header = append(header, k)
+	}
+	sort.Strings(header)
+
+	var rows [][]string
+	for _, flat := range flattened {
+		row := make([]string, len(header))
+		for i, h := range header {
+			row[i] = flat[h]
+		}
+		rows = append(rows, row)
+	}
+
+	return header, rows, nil
+}
+
+// toGeneric round-trips input through JSON so renderers deal only with maps, slices and scalars
+func toGeneric(input interface{}) (interface{}, error) {
+	var generic interface{}
+
+	d := json.NewDecoder(strings.NewReader(outputJSON(input)))
+	d.UseNumber()
+	if err := d.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	return generic, nil
+}
+
+func toRecords(generic interface{}) []interface{} {
+	if list, ok := generic.([]interface{}); ok {
+		return list
+	}
+
+	return []interface{}{generic}
+}
+
+func flatten(prefix string, value interface{}, out map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flatten(key, item, out)
+		}
+	case []interface{}:
+		key := prefix
+		if key == "" {
+			key = "value"
+		}
+		out[key] = outputJSON(v)
+	case nil:
+		out[columnName(prefix)] = ""
+	case string:
+		out[columnName(prefix)] = v
+	case bool:
+		out[columnName(prefix)] = strconv.FormatBool(v)
+	default:
+		out[columnName(prefix)] = fmt.Sprintf("%v", v)
+	}
+}
+
+func columnName(prefix string) string {
+	if prefix == "" {
+		return "value"
+	}
+
+	return prefix
+}
+
+func unescapeUnicodeCharactersInJSON(_jsonRaw json.RawMessage) (json.RawMessage, error) {
+	str, err := strconv.Unquote(strings.Replace(strconv.Quote(string(_jsonRaw)), `\\u`, `\u`, -1))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(str), nil
+}
+
+func outputJSON(input interface{}) string {
+	b, err := json.Marshal(input)
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	jsonRawUnescaped, _ := unescapeUnicodeCharactersInJSON(json.RawMessage(b))
+
+	return string(jsonRawUnescaped)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOutputFormats(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name:     "table",
+			args:     []string{"--output", "table", "ghost", "locations"},
+			contains: []string{"ID                 VALUE", "frankfurt-germany  Frankfurt, Germany"},
+		},
+		{
+			name:     "table single object",
+			args:     []string{"--output", "table", "gtm", "ip-addresses", "--domain", "example.akadns.net", "www"},
+			contains: []string{"FIELD      VALUE", "property   www"},
+		},
+		{
+			name:     "table ip result",
+			args:     []string{"--output", "table", "ip", "is-cdn-ip", "2a02:26f0:d8:0:0:0:17d4:9d1"},
+			contains: []string{"IP                      FAMILY  ISCDNIP", "2a02:26f0:d8::17d4:9d1  IPv6    true"},
+		},
+		{
+			name:     "yaml",
+			args:     []string{"--output", "yaml", "gtm", "properties"},
+			contains: []string{"- domain: example.akadns.net\n  hostName: www.example.akadns.net\n  property: www\n"},
+		},
+		{
+			name:     "csv",
+			args:     []string{"--output", "csv", "ghost", "locations"},
+			contains: []string{"id,value,city,country,countryCode,continent\n", "paris-france,\"Paris, France\",Paris,France,FR,EU\n"},
+		},
+		{
+			name:     "ndjson",
+			args:     []string{"--output", "ndjson", "diagnostic-link", "list"},
+			contains: []string{`{"name":"beloved-customer","requestId":1234,"url":"https://www.example.com/","timestamp":"2026-10-01T10:00:00Z"}` + "\n"},
+		},
+		{
+			name:     "csv flattens nested objects",
+			args:     []string{"--output", "csv", "ip", "curl", "--url", "https://www.example.com/", "23.15.7.10"},
+			contains: []string{"httpStatusCode,responseBody,responseHeaders.Content-Length,responseHeaders.Content-Type,", "200,<html></html>,42,text/html,"},
+		},
+	})
+}
+
+func TestUnknownOutputFormat(t *testing.T) {
+	api := newFakeAPI()
+	defer api.Close()
+
+	_, err := runCommand(t, api, "--output", "xml", "ghost", "locations")
+	if code := exitCode(err); code != exitInvalidFlag {
+		t.Fatalf("exit code = %d, want %d (error: %v)", code, exitInvalidFlag, err)
+	}
+
+	if calls := api.calls(); len(calls) > 0 {
+		t.Errorf("API called before output format was validated: %v", calls)
+	}
+}
+
+func TestTableData(t *testing.T) {
+	input := []map[string]interface{}{
+		{"id": "a", "nested": map[string]interface{}{"x": 1}},
+		{"id": "b", "list": []int{1, 2}},
+	}
+
+	header, rows, err := tableData(input, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(header, ","); got != "id,list,nested.x" {
+		t.Errorf("header = %s", got)
+	}
+
+	if len(rows) != 2 || strings.Join(rows[0], ",") != "a,,1" || strings.Join(rows[1], ",") != "b,[1,2]," {
+		t.Errorf("rows = %v", rows)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// progressWriter is where progress of long running operations is reported
+var progressWriter io.Writer = os.Stderr
+
+// progress reports elapsed time and number of polls of long running operation.
+// On terminal single line is refreshed every second, otherwise line is written per poll.
+type progress struct {
+	label string
+	start time.Time
+	tty   bool
+
+	mu    sync.Mutex
+	polls int
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func startProgress(label string) *progress {
+	p := &progress{
+		label: label,
+		start: time.Now(),
+		tty:   isTerminal(progressWriter),
+		done:  make(chan struct{}),
+	}
+
+	if p.tty {
+		p.wg.Add(1)
+		go p.refresh()
+	}
+
+	return p
+}
+
+func (p *progress) poll(attempt int) {
+	p.mu.Lock()
+	p.polls = attempt
+	p.mu.Unlock()
+
+	if !p.tty {
+		fmt.Fprintln(progressWriter, p.line())
+	}
+}
+
+func (p *progress) stop() {
+	close(p.done)
+	p.wg.Wait()
+
+	if p.tty {
+		fmt.Fprintf(progressWriter, "\r%s\n", p.line())
+	}
+}
+
+func (p *progress) refresh() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		fmt.Fprintf(progressWriter, "\r%s", p.line())
+
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *progress) line() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return fmt.Sprintf("%s: %s elapsed, poll %d", p.label, time.Since(p.start).Round(time.Second), p.polls)
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
-	"os"
+	"context"
+	"fmt"
 	"strings"
+	"time"
 
-	common "github.com/apiheat/akamai-cli-common"
+	service "github.com/apiheat/go-edgegrid/v6/service/diagnosticv2"
 	"github.com/urfave/cli"
 
 	log "github.com/sirupsen/logrus"
@@ -33,50 +35,320 @@ func validateErrorString(err string) string {
 	return errorString
 }
 
+func cmdListTranslateErrorRequests(c *cli.Context) error {
+	return listErrorRequests(c)
+}
+
+// translateJobResult is outcome of retrieving single translate error request
+type translateJobResult struct {
+	RequestID   string                   `json:"requestId"`
+	ErrorString string                   `json:"errorString"`
+	Result      *service.TranslatedError `json:"result,omitempty"`
+	Error       string                   `json:"error,omitempty"`
+}
+
+// withJobStore applies fn to local job store and saves it. Job store is only
+// a convenience, so problems with it are reported but never fail the command.
+func withJobStore(fn func(store *jobStore)) {
+	jobStoreMu.Lock()
+	defer jobStoreMu.Unlock()
+
+	store, err := loadJobStore()
+	if err != nil {
+		log.Warnf("Cannot load translate error requests store: %s", err)
+		return
+	}
+
+	fn(store)
+
+	if err := store.save(); err != nil {
+		log.Warnf("Cannot save translate error requests store: %s", err)
+	}
+}
+
+// jobStatus tells whether translation is still being processed based on check response
+func jobStatus(response *service.TranslateErrorAsync) string {
+	if response.RetryAfter > 0 {
+		return jobStatusPending
+	}
+
+	return jobStatusReady
+}
+
 func launchErrorRequest(c *cli.Context) error {
-	errorString := validateErrorString(common.SetStringId(c, "Please provide Error Code"))
+	errorString, err := requireArgument(c, "Please provide Error Code")
+	if err != nil {
+		return err
+	}
+	errorString = validateErrorString(errorString)
 
 	response, err := apiClient.LaunchTranslateErrorAsync(errorString)
-	common.ErrorCheck(err)
+	if err != nil {
+		return newAPIError(err)
+	}
 
-	common.PrintJSON(outputJSON(response))
+	withJobStore(func(store *jobStore) {
+		store.add(&translateJob{
+			RequestID:   response.RequestID,
+			ErrorString: errorString,
+			LaunchedAt:  time.Now(),
+			RetryAfter:  response.RetryAfter,
+			Status:      jobStatusPending,
+		})
+	})
 
-	return nil
+	return printOutput(c, response)
 }
 
 func checkErrorRequest(c *cli.Context) error {
-	requestID := common.SetStringId(c, "Please provide RequestID from 'launch' command output")
+	requestID, err := requireArgument(c, "Please provide RequestID from 'launch' command output")
+	if err != nil {
+		return err
+	}
 
 	response, err := apiClient.CheckTranslateErrorAsync(requestID)
-	common.ErrorCheck(err)
+	if err != nil {
+		return newAPIError(err)
+	}
 
-	common.PrintJSON(outputJSON(response))
+	withJobStore(func(store *jobStore) {
+		store.updateJob(requestID, jobStatus(response), nil)
+	})
 
-	return nil
+	return printOutput(c, response)
 }
 
 func getErrorRequest(c *cli.Context) error {
-	requestID := common.SetStringId(c, "Please provide RequestID from 'launch' command output")
+	if c.Bool("all-ready") {
+		return getReadyErrorRequests(c)
+	}
+
+	var requestID string
+	if c.Bool("latest") {
+		store, err := loadJobStore()
+		if err != nil {
+			return err
+		}
+
+		job := store.latest()
+		if job == nil {
+			return notFoundError{err: fmt.Errorf("No translate error requests were launched yet")}
+		}
+
+		requestID = job.RequestID
+		log.Infof("Retrieving latest translate error request %s for '%s'", requestID, job.ErrorString)
+	} else {
+		var err error
+		requestID, err = requireArgument(c, "Please provide RequestID from 'launch' command output or use --latest")
+		if err != nil {
+			return err
+		}
+	}
+
+	var errorString string
+	withJobStore(func(store *jobStore) {
+		if job := store.find(requestID); job != nil {
+			errorString = job.ErrorString
+		}
+	})
+
+	if errorString != "" {
+		if cached := cachedTranslation(errorString, c); cached != nil {
+			return printOutput(c, cached)
+		}
+	}
+
+	response, err := retrieveErrorRequest(requestID)
+	if err != nil {
+		return newAPIError(err)
+	}
+
+	return printOutput(c, response)
+}
+
+// getReadyErrorRequests retrieves results of all launched requests which finished processing
+func getReadyErrorRequests(c *cli.Context) error {
+	store, err := loadJobStore()
+	if err != nil {
+		return err
+	}
+
+	results := []translateJobResult{}
+	for _, job := range store.unfetched() {
+		check, err := apiClient.CheckTranslateErrorAsync(job.RequestID)
+		if err != nil {
+			log.Warnf("Cannot check translate error request %s: %s", job.RequestID, errorText(err))
+			continue
+		}
+
+		if jobStatus(check) == jobStatusPending {
+			log.Infof("Translate error request %s for '%s' is still being processed", job.RequestID, job.ErrorString)
+			continue
+		}
 
+		result := translateJobResult{RequestID: job.RequestID, ErrorString: job.ErrorString}
+		result.Result, err = retrieveErrorRequest(job.RequestID)
+		if err != nil {
+			result.Error = errorText(err)
+		}
+
+		results = append(results, result)
+	}
+
+	log.Infof("Retrieved %d finished translate error requests", len(results))
+
+	return printOutput(c, results)
+}
+
+// retrieveErrorRequest gets translated error and records the outcome in job store
+func retrieveErrorRequest(requestID string) (*service.TranslatedError, error) {
 	response, err := apiClient.RetrieveTranslateErrorAsync(requestID)
-	common.ErrorCheck(err)
 
-	common.PrintJSON(outputJSON(response))
+	var errorString string
+	withJobStore(func(store *jobStore) {
+		if job := store.find(requestID); job != nil {
+			errorString = job.ErrorString
+		}
 
-	return nil
+		switch {
+		case err == nil:
+			store.updateJob(requestID, jobStatusFetched, nil)
+		case exitCode(newAPIError(err)) == exitNotFound:
+			store.updateJob(requestID, jobStatusFailed, err)
+		default:
+			if job := store.find(requestID); job != nil {
+				store.updateJob(requestID, job.Status, err)
+			}
+		}
+	})
+
+	if err == nil {
+		cacheTranslation(errorString, requestID, response)
+	}
+
+	return response, err
+}
+
+func listErrorRequests(c *cli.Context) error {
+	store, err := loadJobStore()
+	if err != nil {
+		return err
+	}
+
+	jobs := translateJobs{}
+	for _, job := range store.Jobs {
+		if c.String("status") == "" || job.Status == c.String("status") {
+			jobs = append(jobs, job)
+		}
+	}
+
+	return printOutput(c, jobs)
 }
 
 func translateError(c *cli.Context) error {
-	// Run request
-	errorString := validateErrorString(common.SetStringId(c, "Please provide Error Code"))
+	if c.String("from-log") != "" {
+		if c.String("input") != "" {
+			return newValidationError("Please use either --from-log or --input, not both")
+		}
+
+		return translateFromLog(c)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	// Progress of many concurrent translations would only garble the terminal
+	showProgress := c.String("input") == "" && !c.Bool("no-progress")
+
+	return runOnTargets(c, "Please provide Error Code", func(target string, f flagSource) (interface{}, error) {
+		response, err := translateCached(ctx, validateErrorString(target), f, showProgress)
+		if err != nil {
+			return nil, err
+		}
 
-	response, err := apiClient.TranslateErrorAsync(errorString, c.Int("retries"))
+		return response.TranslatedError, nil
+	})
+}
+
+// translateCached returns cached translation of error string or translates it with launchAndWait
+func translateCached(ctx context.Context, errorString string, f flagSource, showProgress bool) (*service.TranslatedError, error) {
+	if cached := cachedTranslation(errorString, f); cached != nil {
+		return cached, nil
+	}
+
+	return launchAndWait(ctx, errorString, f.Duration("timeout"), f.Int("retries"), showProgress)
+}
+
+// launchTranslation launches translate error request. When API rate limits
+// launches, which happens when many translations run concurrently, it waits
+// at least Retry-After and tries again until ctx is done.
+func launchTranslation(ctx context.Context, errorString string) (*service.TranslateErrorAsync, error) {
+	for attempt := 0; ; attempt++ {
+		launched, err := apiClient.LaunchTranslateErrorAsync(errorString)
+
+		limited, ok := err.(rateLimitError)
+		if !ok {
+			return launched, newAPIError(err)
+		}
+
+		delay := pollDelay(attempt, limited.RetryAfter)
+		log.Debugf("Launching translation of '%s' is rate limited, trying again in %s", errorString, delay)
+
+		select {
+		case <-ctx.Done():
+			return nil, waitError(ctx, "")
+		case <-time.After(delay):
+		}
+	}
+}
+
+// launchAndWait launches translate error request, records it in job store
+// and polls it until it is processed, timeout expires or polls run out
+func launchAndWait(parent context.Context, errorString string, timeout time.Duration, maxPolls int, showProgress bool) (*service.TranslatedError, error) {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	launched, err := launchTranslation(ctx, errorString)
 	if err != nil {
-		common.PrintJSON(outputJSON(err))
-		os.Exit(0)
+		return nil, err
+	}
+
+	withJobStore(func(store *jobStore) {
+		store.add(&translateJob{
+			RequestID:   launched.RequestID,
+			ErrorString: errorString,
+			LaunchedAt:  time.Now(),
+			RetryAfter:  launched.RetryAfter,
+			Status:      jobStatusPending,
+		})
+	})
+
+	var p *progress
+	if showProgress {
+		p = startProgress(fmt.Sprintf("Translating %s ( request ID %s )", errorString, launched.RequestID))
 	}
 
-	common.PrintJSON(outputJSON(response.TranslatedError))
+	response, err := waitForTranslation(ctx, launched.RequestID, func(attempt int) {
+		if maxPolls > 0 && attempt > maxPolls {
+			cancel()
+			return
+		}
+
+		if p != nil {
+			p.poll(attempt)
+		}
+	})
+
+	if p != nil {
+		p.stop()
+	}
+
+	if _, ok := err.(timeoutError); ok {
+		return nil, timeoutError{
+			msg:       fmt.Sprintf("Translation of '%s' did not finish, resume it later with 'translate-request get %s'", errorString, launched.RequestID),
+			RequestID: launched.RequestID,
+		}
+	}
 
-	return nil
+	return response, err
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTranslateErrorCommands(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name:     "launch",
+			args:     []string{"translate-request", "launch", "#18.6f64d440.1318965461.2f2b078"},
+			contains: []string{`"requestId": "req-18.6f64d440.1318965461.2f2b078"`},
+		},
+		{
+			name:     "launch without error code",
+			args:     []string{"translate-request", "launch"},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "check",
+			args:     []string{"translate-request", "check", "req-1"},
+			contains: []string{`"requestId": "req-1"`},
+		},
+		{
+			name:     "get",
+			args:     []string{"translate-request", "get", "req-1"},
+			contains: []string{`"reasonForFailure": "Connection to origin server timed out"`},
+		},
+		{
+			name:     "get unknown request",
+			args:     []string{"translate-request", "get", fakeMissing},
+			exitCode: exitNotFound,
+		},
+		{
+			name:     "translate-error",
+			args:     []string{"translate-error", "18.6f64d440.1318965461.2f2b078"},
+			contains: []string{`"originHostname": "origin.example.com"`},
+		},
+		{
+			name:     "translate-error without error code",
+			args:     []string{"translate-error"},
+			exitCode: exitValidation,
+		},
+		{
+			name:     "translate-error forbidden",
+			args:     []string{"translate-error", fakeForbidden},
+			exitCode: exitAuth,
+		},
+	})
+}
+
+func TestTranslateErrorTimeout(t *testing.T) {
+	var progressOut bytes.Buffer
+	progressWriter = &progressOut
+	defer func() { progressWriter = os.Stderr }()
+
+	runCommandTests(t, []commandTest{
+		{
+			name:     "translate-error times out",
+			args:     []string{"translate-error", "--timeout", "200ms", "18." + fakePending},
+			exitCode: exitTimeout,
+		},
+		{
+			name:     "translate-error out of polls",
+			args:     []string{"translate-error", "--retries", "1", "18.another-" + fakePending},
+			exitCode: exitTimeout,
+		},
+		{
+			name:     "timed out request can be resumed",
+			args:     []string{"translate-request", "list", "--status", "pending"},
+			contains: []string{`"requestId": "req-18.pending"`, `"requestId": "req-18.another-pending"`},
+		},
+	})
+
+	if !strings.Contains(progressOut.String(), "Translating 18.pending ( request ID req-18.pending ): 0s elapsed, poll 1") {
+		t.Errorf("progress was not reported:\n%s", progressOut.String())
+	}
+}
+
+func TestTranslateErrorRateLimited(t *testing.T) {
+	pollInitialDelay = time.Millisecond
+	defer func() { pollInitialDelay = 2 * time.Second }()
+
+	api := newFakeAPI()
+	defer api.Close()
+
+	out, err := runCommand(t, api, "translate-error", fakeThrottled)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out, `"originHostname": "origin.example.com"`) {
+		t.Errorf("output does not contain translated error:\n%s", out)
+	}
+
+	launches := 0
+	for _, call := range api.calls() {
+		if call == "POST "+fakeBasePath+"errors/"+fakeThrottled+"/translate-error" {
+			launches++
+		}
+	}
+
+	if launches != 2 {
+		t.Errorf("translation launched %d times, want 2", launches)
+	}
+}
+
+func TestValidateErrorString(t *testing.T) {
+	if got := validateErrorString("#18.6f64d440.1318965461.2f2b078"); got != "18.6f64d440.1318965461.2f2b078" {
+		t.Errorf("validateErrorString() = %q", got)
+	}
+}
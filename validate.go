@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	common "github.com/apiheat/akamai-cli-common"
+)
+
+var allowedQueries = []string{"A", "AAAA", "CNAME", "MX", "NS", "PTR", "SOA"}
+
+func validateDigOptions(f flagSource) error {
+	if err := validateHostnameFlag(f, "hostname"); err != nil {
+		return err
+	}
+
+	if !common.IsStringInSlice(f.String("query-type"), allowedQueries) {
+		return newQueryTypeError("Provided correct 'query-type': A, AAAA, CNAME, MX, NS, PTR, or SOA")
+	}
+
+	return nil
+}
+
+func validateMtrOptions(f flagSource) error {
+	return validateHostnameFlag(f, "destination-domain")
+}
+
+func validateCurlOptions(f flagSource) error {
+	if f.String("url") == "" {
+		return newFlagError("Provide url, this is required parameter")
+	}
+
+	if _, err := url.Parse(f.String("url")); err != nil {
+		return newFlagError("'url' is not valid URL: %s'", f.String("url"))
+	}
+
+	if method := f.String("method"); method != "" && !common.IsStringInSlice(strings.ToUpper(method), curlMethods) {
+		return newFlagError("Provide correct 'method': %s", strings.Join(curlMethods, ", "))
+	}
+
+	if f.Bool("headers-only") && f.String("method") != "" {
+		return newValidationError("Please use either --headers-only or --method, not both")
+	}
+
+	for _, header := range f.StringSlice("header") {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.ContainsAny(strings.TrimSpace(parts[0]), " \t") {
+			return newFlagError("Header '%s' is not valid, use 'Name: value' form", header)
+		}
+	}
+
+	return nil
+}
+
+// validateHostnameFlag checks that required flag holds hostname without HTTP scheme
+func validateHostnameFlag(f flagSource, name string) error {
+	value := f.String(name)
+
+	if value == "" {
+		return newFlagError("Provide %s, this is required parameter", name)
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return newFlagError("'%s' is not valid URL: %s'", name, value)
+	}
+
+	if u.Scheme != "" {
+		return newFlagError("Please do not provide HTTP scheme in '%s' : %s'", name, value)
+	}
+
+	return nil
+}
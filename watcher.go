@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	service "github.com/apiheat/go-edgegrid/v6/service/diagnosticv2"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+var (
+	// pollInitialDelay and pollMaxDelay bound exponential backoff between
+	// polls of translate error request, Retry-After from API takes precedence
+	pollInitialDelay = 2 * time.Second
+	pollMaxDelay     = 30 * time.Second
+)
+
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
+
+func cmdWaitTranslateErrorRequests(c *cli.Context) error {
+	return waitErrorRequests(c)
+}
+
+// pollDelay returns how long to wait before next poll. It grows exponentially
+// with attempt, is randomised to spread polls of many requests and is never
+// shorter than Retry-After returned by API.
+func pollDelay(attempt, retryAfter int) time.Duration {
+	backoff := pollInitialDelay
+	for i := 0; i < attempt && backoff < pollMaxDelay; i++ {
+		backoff *= 2
+	}
+	if backoff > pollMaxDelay {
+		backoff = pollMaxDelay
+	}
+
+	// Equal jitter, half of the backoff is kept and the other half is random
+	delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+
+	if ra := time.Duration(retryAfter) * time.Second; ra > delay {
+		return ra
+	}
+
+	return delay
+}
+
+// interruptContext returns context which is cancelled when user presses Ctrl-C
+func interruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+			log.Warn("Interrupted, stopping")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// waitForTranslation polls translate error request until it is processed and
+// returns translated error. onPoll, when set, is called before every poll.
+func waitForTranslation(ctx context.Context, requestID string, onPoll func(attempt int)) (*service.TranslatedError, error) {
+	for attempt := 0; ; attempt++ {
+		if onPoll != nil {
+			onPoll(attempt + 1)
+		}
+
+		if ctx.Err() != nil {
+			return nil, waitError(ctx, requestID)
+		}
+
+		check, err := apiClient.CheckTranslateErrorAsync(requestID)
+		if err != nil {
+			return nil, newAPIError(err)
+		}
+
+		withJobStore(func(store *jobStore) {
+			store.updateJob(requestID, jobStatus(check), nil)
+		})
+
+		if jobStatus(check) != jobStatusPending {
+			response, err := retrieveErrorRequest(requestID)
+			if err != nil {
+				return nil, newAPIError(err)
+			}
+
+			return response, nil
+		}
+
+		delay := pollDelay(attempt, check.RetryAfter)
+		log.Debugf("Translate error request %s is still being processed, next poll in %s", requestID, delay)
+
+		select {
+		case <-ctx.Done():
+			return nil, waitError(ctx, requestID)
+		case <-time.After(delay):
+		}
+	}
+}
+
+// waitError tells why waiting for translate error request stopped
+func waitError(ctx context.Context, requestID string) error {
+	if ctx.Err() == context.Canceled {
+		return timeoutError{msg: "Waiting for translate error request was interrupted", RequestID: requestID}
+	}
+
+	return timeoutError{msg: "Translate error request did not finish in time", RequestID: requestID}
+}
+
+// waitErrorRequests polls many translate error requests concurrently. With
+// ndjson output every translated error is printed as soon as it is ready,
+// other formats print all of them as one document in order of request IDs.
+func waitErrorRequests(c *cli.Context) error {
+	requestIDs := []string(c.Args())
+
+	if len(requestIDs) == 0 {
+		store, err := loadJobStore()
+		if err != nil {
+			return err
+		}
+
+		for _, job := range store.unfetched() {
+			requestIDs = append(requestIDs, job.RequestID)
+		}
+
+		if len(requestIDs) == 0 {
+			return newValidationError("Please provide REQUEST_ID(s), there are no pending translate error requests")
+		}
+	}
+
+	parent, stop := interruptContext()
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(parent, c.Duration("timeout"))
+	defer cancel()
+
+	type outcome struct {
+		idx    int
+		result translateJobResult
+		err    error
+	}
+
+	var (
+		wg       sync.WaitGroup
+		outcomes = make(chan outcome, len(requestIDs))
+	)
+
+	for idx, requestID := range requestIDs {
+		wg.Add(1)
+		go func(idx int, requestID string) {
+			defer wg.Done()
+
+			result := translateJobResult{RequestID: requestID}
+			withJobStore(func(store *jobStore) {
+				if job := store.find(requestID); job != nil {
+					result.ErrorString = job.ErrorString
+				}
+			})
+
+			response, err := waitForTranslation(ctx, requestID, nil)
+			if err != nil {
+				result.Error = errorText(err)
+			}
+			result.Result = response
+
+			outcomes <- outcome{idx: idx, result: result, err: err}
+		}(idx, requestID)
+	}
+
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	streaming := strings.ToLower(c.GlobalString("output")) == outputFormatNDJSON
+	results := make([]translateJobResult, len(requestIDs))
+
+	var failed, timedOut []string
+	for o := range outcomes {
+		results[o.idx] = o.result
+		if streaming {
+			if err := printOutput(c, o.result); err != nil {
+				return err
+			}
+		}
+
+		switch o.err.(type) {
+		case nil:
+		case timeoutError:
+			timedOut = append(timedOut, o.result.RequestID)
+		default:
+			failed = append(failed, o.result.RequestID)
+		}
+	}
+
+	if !streaming {
+		if err := printOutput(c, results); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case len(timedOut) > 0:
+		return timeoutError{msg: "Translate error requests did not finish in time, resume them with 'translate-request wait'", RequestID: strings.Join(timedOut, ", ")}
+	case len(failed) > 0:
+		return apiError{err: fmt.Errorf("Translate error requests failed: %s", strings.Join(failed, ", "))}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPollDelay(t *testing.T) {
+	tests := []struct {
+		attempt    int
+		retryAfter int
+		min, max   time.Duration
+	}{
+		{0, 0, pollInitialDelay / 2, pollInitialDelay},
+		{1, 0, pollInitialDelay, 2 * pollInitialDelay},
+		{3, 0, 4 * pollInitialDelay, 8 * pollInitialDelay},
+		{20, 0, pollMaxDelay / 2, pollMaxDelay},
+		{0, 10, 10 * time.Second, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := pollDelay(tt.attempt, tt.retryAfter); got < tt.min || got > tt.max {
+				t.Errorf("pollDelay(%d, %d) = %s, want between %s and %s", tt.attempt, tt.retryAfter, got, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestTranslateRequestWait(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name:     "wait for many requests",
+			args:     []string{"--output", "ndjson", "translate-request", "wait", "req-1", "req-2"},
+			contains: []string{`{"requestId":"req-1","errorString":"","result":{"translatedError":`, `{"requestId":"req-2","errorString":"","result":{"translatedError":`},
+		},
+		{
+			name:     "wait times out",
+			args:     []string{"--output", "ndjson", "translate-request", "wait", "--timeout", "200ms", "req-3", "req-" + fakePending},
+			exitCode: exitTimeout,
+			contains: []string{`{"requestId":"req-3"`, `{"requestId":"req-pending","errorString":"","error":"Translate error request did not finish in time, request ID: req-pending"}`},
+		},
+		{
+			name:     "wait prints one json document",
+			args:     []string{"translate-request", "wait", "req-1", "req-2"},
+			contains: []string{"[\n    {\n        \"requestId\": \"req-1\"", "},\n    {\n        \"requestId\": \"req-2\""},
+		},
+		{
+			name:     "wait for missing request",
+			args:     []string{"translate-request", "wait", fakeMissing},
+			exitCode: exitAPI,
+			contains: []string{`"error": "Not Found: fake API failure"`},
+		},
+	})
+}